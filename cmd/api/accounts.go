@@ -0,0 +1,325 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func (app *application) listAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Type data.AccountType
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	typeStr := app.readString(qs, "type", "")
+	if typeStr != "" {
+		input.Type = data.AccountTypeFromString(typeStr)
+		v.Check(input.Type != 0, "type", "must be CHECKING, SAVINGS, CREDIT_CARD or CASH")
+	}
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "name")
+	input.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	accounts, metadata, err := app.models.Accounts.GetAll(user.ID, input.Type, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	accountsDTO := []*data.AccountDTO{}
+	for _, a := range accounts {
+		accountsDTO = append(accountsDTO, a.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"accounts": accountsDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createAccountHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.AccountDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	account := dto.ToModel()
+	account.User = user
+	account.Currency = strings.ToUpper(account.Currency)
+
+	v := validator.New()
+
+	if data.ValidateAccount(v, account); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Accounts.Insert(account)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAccount):
+			v.AddError("name", "an account with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/accounts/%d", account.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"account": account.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	account, err := app.models.Accounts.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"account": account.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var dto data.AccountDTO
+	err = app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	account, err := app.models.Accounts.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	if dto.Currency != nil {
+		v.Check(strings.EqualFold(*dto.Currency, account.Currency), "currency", "cannot be changed")
+	}
+
+	balance := account.Balance - account.InitialBalance
+	dto.ToDTOUpdateAccount(account)
+	account.Balance = account.InitialBalance + balance
+
+	if data.ValidateAccount(v, account); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Accounts.Update(account, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateAccount):
+			v.AddError("name", "an account with this name already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"account": account.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteAccountHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Accounts.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "account successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listAccountEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		StartDate *time.Time
+		EndDate   *time.Time
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.StartDate = app.readDate(qs, "start", "2006-01-02")
+	input.EndDate = app.readDate(qs, "end", "2006-01-02")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = "id"
+	input.Filters.SortSafelist = []string{"id"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	account, err := app.models.Accounts.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	entries, metadata, err := app.models.Accounts.GetEntries(account.ID, user.ID, input.StartDate, input.EndDate, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"account": account.ToDTO(), "entries": entries, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) validateTransactionAccount(w http.ResponseWriter, r *http.Request, v *validator.Validator, transaction *data.Transaction, userID int64) bool {
+	account, err := app.models.Accounts.GetByID(transaction.Account.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("account", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	if transaction.Currency == "" {
+		transaction.Currency = account.Currency
+	}
+
+	if transaction.Currency != account.Currency {
+		v.AddError("currency", "must match the account currency")
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+
+	transaction.Account = account
+	return true
+}
+
+func (app *application) listAccountInvoicesHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	account, err := app.models.Accounts.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if account.Type != data.CREDIT_CARD {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateBillingCycle(v, account); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	invoices, err := app.models.Accounts.GetInvoices(account, data.Today())
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"account": account.ToDTO(), "invoices": invoices}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"errors"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"strconv"
+)
+
+func (app *application) listUsersAdminHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Search    string
+		Activated *bool
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Search = app.readString(qs, "search", "")
+
+	if qs.Get("activated") != "" {
+		activated, err := strconv.ParseBool(qs.Get("activated"))
+		if err != nil {
+			v.AddError("activated", "must be true or false")
+		}
+		input.Activated = &activated
+	}
+
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "name", "email", "created_at", "-id", "-name", "-email", "-created_at"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	users, metadata, err := app.models.Users.GetAll(input.Search, input.Activated, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	usersDTO := []*data.UserAdminDTO{}
+	for _, u := range users {
+		usersDTO = append(usersDTO, u.ToAdminDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"users": usersDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) activateUserAdminHandler(w http.ResponseWriter, r *http.Request) {
+	app.setUserActivated(w, r, true)
+}
+
+func (app *application) deactivateUserAdminHandler(w http.ResponseWriter, r *http.Request) {
+	app.setUserActivated(w, r, false)
+}
+
+func (app *application) setUserActivated(w http.ResponseWriter, r *http.Request, activated bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if !activated && id == app.contextGetUser(r).ID {
+		v := validator.New()
+		v.AddError("user", "you cannot deactivate your own account")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	previous, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.SetActivated(id, activated)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if activated && !previous.Activated {
+		app.seedDefaultCategories(user)
+	}
+
+	if !activated {
+		err = app.models.Tokens.RevokeAllForUser(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user.ToAdminDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listPermissionsAdminHandler(w http.ResponseWriter, r *http.Request) {
+	permissions, err := app.models.Permissions.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listUserPermissionsAdminHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	app.writeUserPermissions(w, r, user)
+}
+
+func (app *application) grantUserPermissionsAdminHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Permissions []string `json:"permissions"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	available, err := app.models.Permissions.GetAll()
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Permissions) > 0, "permissions", "must contain at least one code")
+	v.Check(validator.Unique(input.Permissions), "permissions", "must not contain duplicate values")
+
+	for _, code := range input.Permissions {
+		v.Check(available.Include(code), "permissions", "must contain only known permission codes")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Permissions.AddForUser(user.ID, input.Permissions...)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeUserPermissions(w, r, user)
+}
+
+func (app *application) revokeUserPermissionAdminHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := app.readAdminTargetUser(w, r)
+	if !ok {
+		return
+	}
+
+	code, err := app.readStrParam(r, "code")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	if code == "users:admin" && user.ID == app.contextGetUser(r).ID {
+		v := validator.New()
+		v.AddError("permissions", "you cannot revoke your own admin permission")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Permissions.RemoveForUser(user.ID, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeUserPermissions(w, r, user)
+}
+
+func (app *application) readAdminTargetUser(w http.ResponseWriter, r *http.Request) (*data.User, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	user, err := app.models.Users.GetByID(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return user, true
+}
+
+func (app *application) writeUserPermissions(w http.ResponseWriter, r *http.Request, user *data.User) {
+	permissions, err := app.models.Permissions.GetAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if permissions == nil {
+		permissions = data.Permissions{}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user.ToAdminDTO(), "permissions": permissions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"time"
+)
+
+func (app *application) listBudgetsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "amount", "-id", "-amount"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	budgets, metadata, err := app.models.Budgets.GetAll(user.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	budgetsDTO := []*data.BudgetDTO{}
+	for _, b := range budgets {
+		err = prepareBudgetForResponse(app, b, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		budgetsDTO = append(budgetsDTO, b.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"budgets": budgetsDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.BudgetDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	dto.User = user.ToDTO()
+	budget := dto.ToModel()
+
+	v := validator.New()
+
+	if data.ValidateBudget(v, budget); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.validateBudgetCategory(w, r, v, budget.Category.ID, user.ID) {
+		return
+	}
+
+	err = app.models.Budgets.Insert(budget)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateBudget):
+			v.AddError("category", "a budget for this category already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareBudgetForResponse(app, budget, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/budgets/%d", budget.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"budget": budget.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	budget, err := app.models.Budgets.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareBudgetForResponse(app, budget, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"budget": budget.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var dto data.BudgetDTO
+	err = app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	budget, err := app.models.Budgets.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	dto.ToDTOUpdateBudget(budget)
+
+	v := validator.New()
+	if data.ValidateBudget(v, budget); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if dto.Category != nil && !app.validateBudgetCategory(w, r, v, budget.Category.ID, user.ID) {
+		return
+	}
+
+	err = app.models.Budgets.Update(budget, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrDuplicateBudget):
+			v.AddError("category", "a budget for this category already exists")
+			app.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareBudgetForResponse(app, budget, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"budget": budget.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteBudgetHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Budgets.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "budget successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) budgetStatusHandler(w http.ResponseWriter, r *http.Request) {
+	qs := r.URL.Query()
+
+	month := time.Now()
+	if qs.Get("month") != "" {
+		m := app.readDate(qs, "month", "2006-01")
+		if m == nil {
+			v := validator.New()
+			v.AddError("month", "must be in the format YYYY-MM")
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+		month = *m
+	}
+
+	user := app.contextGetUser(r)
+	statuses, err := app.models.Budgets.GetStatus(user.ID, month)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"budgets": statuses}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) validateBudgetCategory(w http.ResponseWriter, r *http.Request, v *validator.Validator, categoryID int64, userID int64) bool {
+	category, err := app.models.Categories.GetByID(categoryID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	if category.Type != data.DESPESA {
+		v.AddError("category", "must be a DESPESA category")
+		app.failedValidationResponse(w, r, v.Errors)
+		return false
+	}
+
+	return true
+}
+
+func prepareBudgetForResponse(app *application, budget *data.Budget, user *data.User) error {
+	budget.User = user
+
+	category, err := app.models.Categories.GetByID(budget.Category.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if category != nil {
+		budget.Category = category
+	}
+
+	return nil
+}
@@ -10,7 +10,8 @@ import (
 
 func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
-		Name string
+		Name     string
+		LedgerID int64
 		data.Filters
 	}
 
@@ -18,6 +19,7 @@ func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Req
 
 	qs := r.URL.Query()
 	input.Name = app.readString(qs, "name", "")
+	input.LedgerID = int64(app.readInt(qs, "ledger_id", 0, v))
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
 	input.Filters.Sort = app.readString(qs, "sort", "id")
@@ -29,7 +31,7 @@ func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	user := app.contextGetUser(r)
-	categories, metadata, err := app.models.Categories.GetAll(input.Name, user.ID, input.Filters)
+	categories, metadata, err := app.models.Categories.GetAll(input.Name, user.ID, input.LedgerID, input.Filters)
 
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
@@ -38,7 +40,11 @@ func (app *application) listCategoriesHandler(w http.ResponseWriter, r *http.Req
 
 	categoriesDTO := []*data.CategoryDTO{}
 	for _, c := range categories {
-		c.User = user
+		c.User, err = app.ledgerUser(c.User, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
 		categoriesDTO = append(categoriesDTO, c.ToDTO())
 	}
 
@@ -63,14 +69,41 @@ func (app *application) createCategoryHandler(w http.ResponseWriter, r *http.Req
 
 	v := validator.New()
 
+	if !app.readCategoryParent(w, r, v, category, user.ID) {
+		return
+	}
+
+	if category.Ledger == nil && category.Parent != nil {
+		category.Ledger = &data.Ledger{ID: category.Parent.Ledger.ID}
+	}
+
 	if data.ValidateCategory(v, category); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
+	ledger, ok := app.resolveWritableLedger(w, r, v, category.Ledger, user)
+	if !ok {
+		return
+	}
+	category.Ledger = ledger
+
+	if category.Parent != nil {
+		if v.Check(category.Parent.Ledger.ID == ledger.ID, "parent_id", "must belong to the same ledger"); !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
+
 	err = app.models.Categories.Insert(category)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrDuplicateName):
+			v.AddError("name", "a category with this name already exists in the ledger")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
@@ -103,7 +136,11 @@ func (app *application) showCategoryHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	category.User = user
+	category.User, err = app.ledgerUser(category.User, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"category": category.ToDTO()}, nil)
 	if err != nil {
@@ -144,7 +181,40 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	if !category.Ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	owner := category.User
+	ledger := category.Ledger
+	previousType := category.Type
 	category = dto.ToDTOUpdateCategory(category)
+	category.User = owner
+	category.Ledger = ledger
+
+	category.Height, err = app.models.Categories.GetSubtreeHeight(category.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if category.Height > 0 {
+		v.Check(category.Type == previousType, "type", "must not be changed while the category has subcategories")
+	}
+
+	if !app.readCategoryParent(w, r, v, category, user.ID) {
+		return
+	}
+
+	if category.Parent != nil {
+		v.Check(category.Parent.Ledger.ID == ledger.ID, "parent_id", "must belong to the same ledger")
+	}
+
+	if data.ValidateCategory(v, category); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
 
 	err = app.models.Categories.Update(category, user.ID)
 	if err != nil {
@@ -157,7 +227,11 @@ func (app *application) updateCategoryHandler(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	category.User = user
+	category.User, err = app.ledgerUser(category.User, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
 	err = app.writeJSON(w, http.StatusOK, envelope{"category": category.ToDTO()}, nil)
 	if err != nil {
@@ -173,7 +247,23 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 	}
 
 	user := app.contextGetUser(r)
-	err = app.models.Categories.Delete(id, user.ID)
+	category, err := app.models.Categories.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if !category.Ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Categories.Delete(category.ID, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -189,3 +279,54 @@ func (app *application) deleteCategoryHandler(w http.ResponseWriter, r *http.Req
 		app.serverErrorResponse(w, r, err)
 	}
 }
+
+func (app *application) showCategoryTreeHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	ledgerID := int64(app.readInt(qs, "ledger_id", 0, v))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	tree, err := app.models.Categories.GetTree(user.ID, ledgerID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"categories": tree}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readCategoryParent(w http.ResponseWriter, r *http.Request, v *validator.Validator, category *data.Category, userID int64) bool {
+	if category.Parent == nil {
+		return true
+	}
+
+	parent, err := app.models.Categories.GetByID(category.Parent.ID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("parent_id", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return false
+	}
+
+	parent.Ancestors, err = app.models.Categories.GetAncestorIDs(parent.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return false
+	}
+
+	category.Parent = parent
+	return true
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const maxRuleApplyTransactions = 10000
+
+func (app *application) listCategorizationRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "priority")
+	input.Filters.SortSafelist = []string{"id", "name", "priority", "-id", "-name", "-priority"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rules, metadata, err := app.models.Rules.GetAll(user.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	rulesDTO := []*data.CategorizationRuleDTO{}
+	for _, rule := range rules {
+		err = prepareRuleForResponse(app, rule, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		rulesDTO = append(rulesDTO, rule.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"rules": rulesDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.CategorizationRuleDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rule := dto.ToModel()
+	rule.User = user
+
+	v := validator.New()
+	if data.ValidateCategorizationRule(v, rule); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.validateRuleReferences(w, r, v, rule, user.ID) {
+		return
+	}
+
+	err = app.models.Rules.Insert(rule)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = prepareRuleForResponse(app, rule, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/categorization-rules/%d", rule.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"rule": rule.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rule, err := app.models.Rules.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareRuleForResponse(app, rule, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"rule": rule.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var dto data.CategorizationRuleDTO
+	err = app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rule, err := app.models.Rules.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	dto.ToDTOUpdateRule(rule)
+
+	v := validator.New()
+	if data.ValidateCategorizationRule(v, rule); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.validateRuleReferences(w, r, v, rule, user.ID) {
+		return
+	}
+
+	err = app.models.Rules.Update(rule, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareRuleForResponse(app, rule, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"rule": rule.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteCategorizationRuleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Rules.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "rule successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) applyCategorizationRulesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		DryRun  *bool      `json:"dry_run"`
+		RuleIDs []int64    `json:"rule_ids"`
+		Start   *data.Date `json:"start"`
+		End     *data.Date `json:"end"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	dryRun := input.DryRun == nil || *input.DryRun
+
+	v := validator.New()
+	if input.Start != nil && input.End != nil {
+		v.Check(!input.End.Before(input.Start.Time), "end", "must not be before start")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rules, err := app.models.Rules.GetActive(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(input.RuleIDs) > 0 {
+		rules = slices.DeleteFunc(rules, func(rule *data.CategorizationRule) bool {
+			return !slices.Contains(input.RuleIDs, rule.ID)
+		})
+	}
+
+	var start, end *time.Time
+	if input.Start != nil {
+		start = &input.Start.Time
+	}
+	if input.End != nil {
+		end = &input.End.Time
+	}
+
+	transactions, err := app.models.Transactions.GetForCategorization(user.ID, start, end, maxRuleApplyTransactions+1)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if len(transactions) > maxRuleApplyTransactions {
+		v.AddError("start", fmt.Sprintf("the period has more than %d transactions, narrow it with start and end", maxRuleApplyTransactions))
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ids := make([]int64, 0, len(transactions))
+	for _, t := range transactions {
+		ids = append(ids, t.ID)
+	}
+
+	split, err := app.models.Splits.GetSplitTransactionIDs(ids)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	matched := 0
+	changes := []*data.CategoryChange{}
+	skipped := []*data.CategoryChange{}
+	for _, t := range transactions {
+		rule := data.MatchCategorizationRule(rules, t, t.Category.Type)
+		if rule == nil {
+			continue
+		}
+		matched++
+
+		if rule.Category.ID == t.Category.ID {
+			continue
+		}
+
+		change := &data.CategoryChange{
+			TransactionID:  t.ID,
+			Description:    t.Description,
+			Amount:         t.Amount,
+			OccurredOn:     t.OccurredOn,
+			FromCategoryID: t.Category.ID,
+			ToCategoryID:   rule.Category.ID,
+			RuleID:         rule.ID,
+		}
+
+		if split[t.ID] && !data.CanMoveSplitTransaction(t.Category, rule.Category) {
+			change.Reason = "the transaction is split and can only move to a DESPESA category of the same ledger"
+			skipped = append(skipped, change)
+			continue
+		}
+
+		changes = append(changes, change)
+	}
+
+	updated := 0
+	if !dryRun && len(changes) > 0 {
+		updated, err = app.models.Rules.ApplyChanges(changes, user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"dry_run":   dryRun,
+		"evaluated": len(transactions),
+		"matched":   matched,
+		"updated":   updated,
+		"changes":   changes,
+		"skipped":   skipped,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) validateRuleReferences(w http.ResponseWriter, r *http.Request, v *validator.Validator, rule *data.CategorizationRule, userID int64) bool {
+	category, ok := app.validateWritableCategory(w, r, v, rule.Category.ID, userID)
+	if !ok {
+		return false
+	}
+	rule.Category = category
+
+	if rule.Account != nil {
+		account, err := app.models.Accounts.GetByID(rule.Account.ID, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrRecordNotFound):
+				v.AddError("account", "does not exist")
+				app.failedValidationResponse(w, r, v.Errors)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return false
+		}
+		rule.Account = account
+	}
+
+	return true
+}
+
+func (app *application) categorizeTransaction(rules []*data.CategorizationRule, transaction *data.Transaction, categoryType data.TypeCategoria) bool {
+	rule := data.MatchCategorizationRule(rules, transaction, categoryType)
+	if rule == nil {
+		return false
+	}
+
+	transaction.Category = rule.Category
+	return true
+}
+
+func prepareRuleForResponse(app *application, rule *data.CategorizationRule, user *data.User) error {
+	rule.User = user
+
+	category, err := app.models.Categories.GetByID(rule.Category.ID, user.ID)
+	if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+		return err
+	}
+
+	if category != nil {
+		rule.Category = category
+	}
+
+	if rule.Account != nil && rule.Account.Name == "" {
+		account, err := app.models.Accounts.GetByID(rule.Account.ID, user.ID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		if account != nil {
+			rule.Account = account
+		}
+	}
+
+	return nil
+}
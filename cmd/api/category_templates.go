@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+func (app *application) listCategoryTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	err := app.writeJSON(w, http.StatusOK, envelope{
+		"templates": app.categoryTemplates,
+		"default":   app.config.categoryTemplates.defaultCode,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) applyCategoryTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Template string `json:"template"`
+		LedgerID *int64 `json:"ledger_id"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	pack := data.FindCategoryTemplatePack(app.categoryTemplates, input.Template)
+	if v.Check(pack != nil, "template", "does not exist"); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var ledger *data.Ledger
+	if input.LedgerID != nil {
+		ledger = &data.Ledger{ID: *input.LedgerID}
+	}
+
+	user := app.contextGetUser(r)
+	ledger, ok := app.resolveWritableLedger(w, r, v, ledger, user)
+	if !ok {
+		return
+	}
+
+	categories, skipped, err := app.models.Categories.ApplyTemplate(pack, user.ID, ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	categoriesDTO := []*data.CategoryDTO{}
+	for _, c := range categories {
+		c.User = user
+		c.Ledger = ledger
+		categoriesDTO = append(categoriesDTO, c.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"template": pack.Code, "created": categoriesDTO, "skipped": skipped}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) seedDefaultCategories(user *data.User) {
+	pack := data.FindCategoryTemplatePack(app.categoryTemplates, app.config.categoryTemplates.defaultCode)
+	if pack == nil {
+		return
+	}
+
+	app.background(func() {
+		ledger, err := app.defaultLedger(user)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"user_id": strconv.FormatInt(user.ID, 10)})
+			return
+		}
+
+		categories, _, err := app.models.Categories.ApplyTemplate(pack, user.ID, ledger.ID)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{"user_id": strconv.FormatInt(user.ID, 10)})
+			return
+		}
+
+		app.logger.PrintInfo("starter categories seeded", map[string]string{
+			"user_id":    strconv.FormatInt(user.ID, 10),
+			"template":   pack.Code,
+			"categories": strconv.Itoa(len(categories)),
+		})
+	})
+}
+
+func (app *application) loadCategoryTemplatesFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	packs, err := data.ReadCategoryTemplatesJSON(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	app.categoryTemplates = packs
+
+	app.logger.PrintInfo("category templates loaded", map[string]string{
+		"file":      path,
+		"templates": strconv.Itoa(len(packs)),
+	})
+
+	return nil
+}
@@ -8,7 +8,10 @@ import (
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey  = contextKey("user")
+	tokenContextKey = contextKey("token")
+)
 
 func (app *application) contextSetUser(r *http.Request, user *data.User) *http.Request {
 	ctx := context.WithValue(r.Context(), userContextKey, user)
@@ -22,3 +25,16 @@ func (app *application) contextGetUser(r *http.Request) *data.User {
 	}
 	return user
 }
+
+func (app *application) contextSetTokenClaims(r *http.Request, claims *tokenClaims) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, claims)
+	return r.WithContext(ctx)
+}
+
+func (app *application) contextGetTokenClaims(r *http.Request) *tokenClaims {
+	claims, ok := r.Context().Value(tokenContextKey).(*tokenClaims)
+	if !ok {
+		panic("missing token claims value in request context")
+	}
+	return claims
+}
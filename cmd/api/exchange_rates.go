@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func (app *application) listExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Base  string
+		Quote string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Base = strings.ToUpper(app.readString(qs, "base", ""))
+	input.Quote = strings.ToUpper(app.readString(qs, "quote", ""))
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-rate_date")
+	input.Filters.SortSafelist = []string{"rate_date", "-rate_date"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rates, metadata, err := app.models.Rates.GetAll(input.Base, input.Quote, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"exchange_rates": rates, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) loadExchangeRatesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Rates []*data.ExchangeRate `json:"rates"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Rates) > 0, "rates", "must contain at least one rate")
+
+	for i, rate := range input.Rates {
+		rate.Base = strings.ToUpper(rate.Base)
+		rate.Quote = strings.ToUpper(rate.Quote)
+		data.ValidateExchangeRate(v, "rates["+strconv.Itoa(i)+"]", rate)
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Rates.Upsert(input.Rates)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"loaded": len(input.Rates)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) loadExchangeRatesFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	rates, err := data.ReadExchangeRatesCSV(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	err = app.models.Rates.Upsert(rates)
+	if err != nil {
+		return err
+	}
+
+	app.logger.PrintInfo("exchange rates loaded", map[string]string{
+		"file":  path,
+		"rates": strconv.Itoa(len(rates)),
+	})
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const exportFlushEvery = 100
+
+func (app *application) exportTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name         string
+		Format       string
+		CategoryType data.TypeCategoria
+		StartDate    *time.Time
+		EndDate      *time.Time
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	categoryStr := app.readString(qs, "category_type", "")
+	if categoryStr != "" {
+		input.CategoryType = data.TypeCategoriaFromString(categoryStr)
+		v.Check(input.CategoryType != 0, "category_type", "must be RECEITA or DESPESA")
+	}
+	input.StartDate = app.readDate(qs, "start", "2006-01-02")
+	input.EndDate = app.readDate(qs, "end", "2006-01-02")
+	input.Name = app.readString(qs, "description", "")
+	input.Format = app.readString(qs, "format", "csv")
+
+	v.Check(validator.In(input.Format, "csv", "ndjson", "spreadsheet"), "format", "must be one of csv, ndjson or spreadsheet")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+
+	rc := http.NewResponseController(w)
+	err := rc.SetWriteDeadline(time.Now().Add(5 * time.Minute))
+	if err != nil {
+		app.logError(r, err)
+	}
+
+	filename := fmt.Sprintf("transactions-%s", time.Now().Format("20060102"))
+
+	var write func(*data.Transaction) error
+	var finish func() error
+	flushed := false
+
+	switch input.Format {
+	case "ndjson":
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".ndjson"))
+
+		enc := json.NewEncoder(w)
+		write = func(t *data.Transaction) error {
+			if t.User.ID == user.ID {
+				t.User = user
+			}
+			flushed = true
+			return enc.Encode(t.ToDTO())
+		}
+		finish = func() error { return nil }
+	default:
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+
+		cw := csv.NewWriter(w)
+		header := []string{"id", "date", "description", "amount", "currency", "category_id", "category", "category_type", "tags"}
+		decimalSeparator := "."
+
+		if input.Format == "spreadsheet" {
+			header[0] = "\ufeff" + header[0]
+			cw.Comma = ';'
+			decimalSeparator = ","
+		}
+
+		err = cw.Write(header)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		write = func(t *data.Transaction) error {
+			amount := t.Amount.String()
+			return cw.Write([]string{
+				strconv.FormatInt(t.ID, 10),
+				t.OccurredOn.String(),
+				t.Description,
+				strings.Replace(amount, ".", decimalSeparator, 1),
+				t.Currency,
+				strconv.FormatInt(t.Category.ID, 10),
+				t.Category.Name,
+				t.Category.Type.String(),
+				strings.Join(t.Tags, "|"),
+			})
+		}
+		finish = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	}
+
+	count := 0
+	err = app.models.Transactions.Export(input.Name, user.ID, input.StartDate, input.EndDate, input.CategoryType, func(t *data.Transaction) error {
+		err := write(t)
+		if err != nil {
+			return err
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if err := finish(); err != nil {
+				return err
+			}
+			flushed = true
+			return rc.Flush()
+		}
+
+		return nil
+	})
+
+	if err != nil && !flushed {
+		w.Header().Del("Content-Disposition")
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err == nil {
+		err = finish()
+	}
+
+	if err != nil {
+		app.logError(r, err)
+	}
+}
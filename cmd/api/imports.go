@@ -0,0 +1,494 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/ofx"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const maxImportFileBytes = 10 << 20
+
+var importDateFormats = map[string]string{
+	"YYYY-MM-DD": "2006-01-02",
+	"DD/MM/YYYY": "02/01/2006",
+	"MM/DD/YYYY": "01/02/2006",
+	"DD-MM-YYYY": "02-01-2006",
+}
+
+type importRowError struct {
+	Row    int               `json:"row"`
+	Errors map[string]string `json:"errors"`
+}
+
+type csvColumns struct {
+	date        int
+	description int
+	amount      int
+}
+
+func (app *application) importCSVTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileBytes)
+	err := r.ParseMultipartForm(maxImportFileBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("body must be a multipart form no larger than %d bytes", maxImportFileBytes))
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("file must be provided"))
+		return
+	}
+	defer file.Close()
+
+	var input struct {
+		DateColumn        string
+		DescriptionColumn string
+		AmountColumn      string
+		DateFormat        string
+		Delimiter         string
+		DecimalSeparator  string
+		HasHeader         bool
+		CategoryID        int64
+		IncomeCategoryID  int64
+		AccountID         int64
+		Currency          string
+	}
+
+	v := validator.New()
+
+	form := r.MultipartForm.Value
+	input.DateColumn = app.readString(form, "date_column", "")
+	input.DescriptionColumn = app.readString(form, "description_column", "")
+	input.AmountColumn = app.readString(form, "amount_column", "")
+	input.DateFormat = app.readString(form, "date_format", "YYYY-MM-DD")
+	input.Delimiter = app.readString(form, "delimiter", ",")
+	input.DecimalSeparator = app.readString(form, "decimal_separator", ".")
+	input.HasHeader = app.readString(form, "has_header", "true") != "false"
+	input.CategoryID = int64(app.readInt(form, "category_id", 0, v))
+	input.IncomeCategoryID = int64(app.readInt(form, "income_category_id", 0, v))
+	input.AccountID = int64(app.readInt(form, "account_id", 0, v))
+	input.Currency = strings.ToUpper(app.readString(form, "currency", ""))
+
+	v.Check(input.DateColumn != "", "date_column", "must be provided")
+	v.Check(input.DescriptionColumn != "", "description_column", "must be provided")
+	v.Check(input.AmountColumn != "", "amount_column", "must be provided")
+	v.Check(importDateFormats[input.DateFormat] != "", "date_format", "must be one of YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY or DD-MM-YYYY")
+	v.Check(utf8.RuneCountInString(input.Delimiter) == 1, "delimiter", "must be a single character")
+	v.Check(validator.In(input.DecimalSeparator, ".", ","), "decimal_separator", "must be . or ,")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if input.IncomeCategoryID == 0 {
+		input.IncomeCategoryID = input.CategoryID
+	}
+
+	user := app.contextGetUser(r)
+	expenseCategory, ok := app.readImportCategory(w, r, v, "category_id", input.CategoryID, user.ID)
+	if !ok {
+		return
+	}
+
+	incomeCategory, ok := app.readImportCategory(w, r, v, "income_category_id", input.IncomeCategoryID, user.ID)
+	if !ok {
+		return
+	}
+
+	account, ok := app.readImportAccount(w, r, v, input.AccountID, user.ID)
+	if !ok {
+		return
+	}
+
+	rules, err := app.models.Rules.GetActive(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if account != nil && input.Currency == "" {
+		input.Currency = account.Currency
+	}
+
+	if account != nil && input.Currency != account.Currency {
+		v.AddError("currency", "must match the account currency")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	reader := csv.NewReader(file)
+	reader.Comma, _ = utf8.DecodeRuneInString(input.Delimiter)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var header []string
+	if input.HasHeader {
+		header, err = reader.Read()
+		if err != nil {
+			app.badRequestResponse(w, r, errors.New("file must contain a header row"))
+			return
+		}
+	}
+
+	columns := csvColumns{}
+	columns.date = csvColumnIndex(header, input.DateColumn)
+	columns.description = csvColumnIndex(header, input.DescriptionColumn)
+	columns.amount = csvColumnIndex(header, input.AmountColumn)
+
+	v.Check(columns.date >= 0, "date_column", "does not match any column")
+	v.Check(columns.description >= 0, "description_column", "does not match any column")
+	v.Check(columns.amount >= 0, "amount_column", "does not match any column")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	transactions := []*data.Transaction{}
+	rowErrors := []importRowError{}
+	categorized := 0
+
+	row := 0
+	if input.HasHeader {
+		row = 1
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		row++
+
+		var parseError *csv.ParseError
+		switch {
+		case errors.As(err, &parseError):
+			rowErrors = append(rowErrors, importRowError{Row: row, Errors: map[string]string{"row": parseError.Err.Error()}})
+			continue
+		case err != nil:
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		if isBlankRecord(record) {
+			continue
+		}
+
+		transaction, categoryType, rv := parseCSVRecord(record, columns, importDateFormats[input.DateFormat], input.DecimalSeparator)
+		transaction.User = user
+		transaction.Account = account
+		transaction.Currency = input.Currency
+
+		matched := app.categorizeTransaction(rules, transaction, categoryType)
+		if !matched {
+			transaction.Category = expenseCategory
+			if categoryType == data.RECEITA {
+				transaction.Category = incomeCategory
+			}
+		}
+
+		if transaction.Category != nil {
+			rv.Check(transaction.Category.Type == categoryType, "category", fmt.Sprintf("must be a %s category for this amount", categoryType))
+		}
+
+		if data.ValidateTransaction(rv, transaction); !rv.Valid() {
+			rowErrors = append(rowErrors, importRowError{Row: row, Errors: rv.Errors})
+			continue
+		}
+
+		if matched {
+			categorized++
+		}
+		transactions = append(transactions, transaction)
+	}
+
+	if len(rowErrors) > 0 {
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, envelope{"rows": rowErrors})
+		return
+	}
+
+	if len(transactions) == 0 {
+		app.badRequestResponse(w, r, errors.New("file does not contain any transactions"))
+		return
+	}
+
+	err = app.models.Transactions.InsertBatch(transactions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"imported": len(transactions), "categorized": categorized}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) importOFXTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportFileBytes)
+	err := r.ParseMultipartForm(maxImportFileBytes)
+	if err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("body must be a multipart form no larger than %d bytes", maxImportFileBytes))
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		app.badRequestResponse(w, r, errors.New("file must be provided"))
+		return
+	}
+	defer file.Close()
+
+	v := validator.New()
+
+	form := r.MultipartForm.Value
+	categoryID := int64(app.readInt(form, "category_id", 0, v))
+	incomeCategoryID := int64(app.readInt(form, "income_category_id", 0, v))
+	accountID := int64(app.readInt(form, "account_id", 0, v))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if incomeCategoryID == 0 {
+		incomeCategoryID = categoryID
+	}
+
+	user := app.contextGetUser(r)
+
+	expenseCategory, ok := app.readImportCategory(w, r, v, "category_id", categoryID, user.ID)
+	if !ok {
+		return
+	}
+
+	incomeCategory, ok := app.readImportCategory(w, r, v, "income_category_id", incomeCategoryID, user.ID)
+	if !ok {
+		return
+	}
+
+	account, ok := app.readImportAccount(w, r, v, accountID, user.ID)
+	if !ok {
+		return
+	}
+
+	rules, err := app.models.Rules.GetActive(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	statements, err := ofx.Parse(file)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	transactions := []*data.Transaction{}
+	failed := []envelope{}
+	categorized := 0
+
+	for _, statement := range statements {
+		for _, entry := range statement.Transactions {
+			if entry.Err != nil {
+				failed = append(failed, envelope{"fitid": entry.FITID, "errors": map[string]string{"entry": entry.Err.Error()}})
+				continue
+			}
+
+			amount, err := data.ParseMoney(entry.Amount)
+			if err != nil {
+				failed = append(failed, envelope{"fitid": entry.FITID, "errors": map[string]string{"amount": err.Error()}})
+				continue
+			}
+
+			transaction := &data.Transaction{
+				User:            user,
+				Description:     entry.Description(),
+				Amount:          amount.Abs(),
+				OccurredOn:      data.NewDate(entry.Posted),
+				FITID:           entry.FITID,
+				ExternalAccount: statement.BankID + ":" + statement.AccountID,
+				Currency:        strings.ToUpper(statement.Currency),
+				Account:         account,
+			}
+
+			categoryType := importCategoryType(amount)
+			matched := app.categorizeTransaction(rules, transaction, categoryType)
+			if !matched {
+				transaction.Category = expenseCategory
+				if categoryType == data.RECEITA {
+					transaction.Category = incomeCategory
+				}
+			}
+
+			if account != nil && transaction.Currency == "" {
+				transaction.Currency = account.Currency
+			}
+
+			ev := validator.New()
+			if transaction.Category != nil {
+				ev.Check(transaction.Category.Type == categoryType, "category", fmt.Sprintf("must be a %s category for this amount", categoryType))
+			}
+			if account != nil {
+				ev.Check(transaction.Currency == account.Currency, "currency", "must match the account currency")
+			}
+
+			if data.ValidateTransaction(ev, transaction); !ev.Valid() {
+				failed = append(failed, envelope{"fitid": entry.FITID, "errors": ev.Errors})
+				continue
+			}
+
+			if matched {
+				categorized++
+			}
+			transactions = append(transactions, transaction)
+		}
+	}
+
+	inserted, err := app.models.Transactions.InsertImported(transactions)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"new":         inserted,
+		"skipped":     len(transactions) - inserted,
+		"failed":      len(failed),
+		"categorized": categorized,
+		"errors":      failed,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readImportCategory(w http.ResponseWriter, r *http.Request, v *validator.Validator, field string, categoryID int64, userID int64) (*data.Category, bool) {
+	if categoryID == 0 {
+		return nil, true
+	}
+
+	category, err := app.models.Categories.GetByID(categoryID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError(field, "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !category.Ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return category, true
+}
+
+func (app *application) readImportAccount(w http.ResponseWriter, r *http.Request, v *validator.Validator, accountID int64, userID int64) (*data.Account, bool) {
+	if accountID == 0 {
+		return nil, true
+	}
+
+	account, err := app.models.Accounts.GetByID(accountID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("account_id", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return account, true
+}
+
+func parseCSVRecord(record []string, columns csvColumns, dateLayout string, decimalSeparator string) (*data.Transaction, data.TypeCategoria, *validator.Validator) {
+	v := validator.New()
+	transaction := &data.Transaction{}
+
+	field := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	date, err := time.Parse(dateLayout, field(columns.date))
+	if err != nil {
+		v.AddError("date", "invalid date")
+	}
+	transaction.OccurredOn = data.NewDate(date)
+
+	transaction.Description = field(columns.description)
+
+	amount, err := parseAmount(field(columns.amount), decimalSeparator)
+	if err != nil {
+		v.AddError("amount", err.Error())
+	}
+	transaction.Amount = amount.Abs()
+
+	return transaction, importCategoryType(amount), v
+}
+
+func parseAmount(s string, decimalSeparator string) (data.Money, error) {
+	s = strings.NewReplacer("R$", "", "$", "", " ", "").Replace(s)
+
+	if decimalSeparator == "," {
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.ReplaceAll(s, ",", ".")
+	} else {
+		s = strings.ReplaceAll(s, ",", "")
+	}
+
+	return data.ParseMoney(s)
+}
+
+func importCategoryType(amount data.Money) data.TypeCategoria {
+	if amount > 0 {
+		return data.RECEITA
+	}
+	return data.DESPESA
+}
+
+func csvColumnIndex(header []string, column string) int {
+	for i, name := range header {
+		if strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column)) {
+			return i
+		}
+	}
+
+	i, err := strconv.Atoi(column)
+	if err != nil || i < 0 {
+		return -1
+	}
+
+	return i
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
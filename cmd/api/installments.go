@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+)
+
+func (app *application) listInstallmentPurchasesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AccountID int64
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.AccountID = int64(app.readInt(qs, "account_id", 0, v))
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-occurred_on")
+	input.Filters.SortSafelist = []string{"id", "amount", "occurred_on", "-id", "-amount", "-occurred_on"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	purchases, metadata, err := app.models.Installments.GetAll(user.ID, input.AccountID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	purchasesDTO := []*data.InstallmentPurchaseDTO{}
+	for _, p := range purchases {
+		purchasesDTO = append(purchasesDTO, p.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"installment_purchases": purchasesDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createInstallmentPurchaseHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.InstallmentPurchaseDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	purchase := dto.ToModel()
+	purchase.User = user
+
+	if purchase.OccurredOn.IsZero() {
+		purchase.OccurredOn = data.Today()
+	}
+
+	v := validator.New()
+
+	if data.ValidateInstallmentPurchase(v, purchase); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	account, err := app.models.Accounts.GetByID(purchase.Account.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("account", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if account.Type != data.CREDIT_CARD {
+		v.AddError("account", "must be a CREDIT_CARD account")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if data.ValidateBillingCycle(v, account); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !app.validateBudgetCategory(w, r, v, purchase.Category.ID, user.ID) {
+		return
+	}
+
+	if _, ok := app.validateWritableCategory(w, r, v, purchase.Category.ID, user.ID); !ok {
+		return
+	}
+
+	purchase.Account = account
+
+	err = app.models.Installments.Insert(purchase)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = prepareInstallmentPurchaseForResponse(app, purchase, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/installment-purchases/%d", purchase.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"installment_purchase": purchase.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showInstallmentPurchaseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	purchase, err := app.models.Installments.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareInstallmentPurchaseForResponse(app, purchase, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"installment_purchase": purchase.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteInstallmentPurchaseHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Installments.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "installment purchase successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func prepareInstallmentPurchaseForResponse(app *application, purchase *data.InstallmentPurchase, user *data.User) error {
+	purchase.User = user
+
+	category, err := app.models.Categories.GetByID(purchase.Category.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if category != nil {
+		purchase.Category = category
+	}
+
+	for _, t := range purchase.Transactions {
+		t.User = nil
+		t.Category = &data.Category{ID: purchase.Category.ID}
+		t.Account = &data.Account{ID: purchase.Account.ID}
+	}
+
+	return nil
+}
@@ -0,0 +1,562 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const ledgerInvitationTTL = 7 * 24 * time.Hour
+
+func (app *application) listLedgersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "name", "-id", "-name"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	ledgers, metadata, err := app.models.Ledgers.GetAll(user.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ledgersDTO := []*data.LedgerDTO{}
+	for _, l := range ledgers {
+		ledgersDTO = append(ledgersDTO, l.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"ledgers": ledgersDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.LedgerDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ledger := dto.ToModel()
+
+	v := validator.New()
+	if data.ValidateLedger(v, ledger); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Ledgers.Insert(ledger, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ledger.Owner = user
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/ledgers/%d", ledger.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"ledger": ledger.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := app.models.Ledgers.GetMembers(ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"ledger": ledger.ToDTO(), "members": ledgerMembersDTO(members)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateLedgerHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var dto data.LedgerDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if dto.Version != nil {
+		ledger.Version = *dto.Version
+	}
+
+	if dto.Name != nil {
+		ledger.Name = strings.TrimSpace(*dto.Name)
+	}
+
+	v := validator.New()
+	if data.ValidateLedger(v, ledger); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ledgers.Update(ledger)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"ledger": ledger.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listLedgerMembersHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := app.models.Ledgers.GetMembers(ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"members": ledgerMembersDTO(members)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateLedgerMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	memberID, err := app.readIntParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Role data.LedgerRole `json:"role"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateInvitedRole(v, input.Role); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ledgers.UpdateMemberRole(ledger.ID, memberID, input.Role)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeLedgerMembers(w, r, ledger)
+}
+
+func (app *application) removeLedgerMemberHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	memberID, err := app.readIntParam(r, "user_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	if memberID != user.ID && !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	if memberID == user.ID && ledger.Role.CanManage() {
+		v := validator.New()
+		v.AddError("user", "the owner cannot leave the ledger")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Ledgers.RemoveMember(ledger.ID, memberID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "member successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listLedgerInvitationsHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	invitations, err := app.models.Ledgers.GetPendingInvitations(ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"invitations": invitations}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createLedgerInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Email string          `json:"email"`
+		Role  data.LedgerRole `json:"role"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	input.Email = strings.ToLower(strings.TrimSpace(input.Email))
+	if input.Role == "" {
+		input.Role = data.LedgerEditor
+	}
+
+	v := validator.New()
+	data.ValidateEmail(v, input.Email)
+	data.ValidateInvitedRole(v, input.Role)
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	invitation := &data.LedgerInvitation{
+		LedgerID:  ledger.ID,
+		Email:     input.Email,
+		Role:      input.Role,
+		InvitedBy: user.ID,
+	}
+
+	err = app.models.Ledgers.InsertInvitation(invitation, ledgerInvitationTTL)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrAlreadyLedgerMember):
+			v.AddError("email", "this user is already a member of the ledger")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	inviterName := user.Name
+	ledgerName := ledger.Name
+
+	app.background(func() {
+		data := map[string]any{
+			"inviterName": inviterName,
+			"ledgerName":  ledgerName,
+			"role":        string(invitation.Role),
+			"token":       invitation.Plaintext,
+			"expiryDays":  int(ledgerInvitationTTL.Hours() / 24),
+		}
+
+		err := app.mailer.Send(invitation.Email, "ledger_invitation.tmpl", data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+
+	err = app.writeJSON(w, http.StatusAccepted, envelope{"invitation": invitation}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteLedgerInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	invitationID, err := app.readIntParam(r, "invitation_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.models.Ledgers.DeleteInvitation(invitationID, ledger.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "invitation successfully cancelled"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) acceptLedgerInvitationHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	if data.ValidateTokenPlaintext(v, input.Token); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	ledgerID, err := app.models.Ledgers.AcceptInvitation(input.Token, user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("token", "invalid or expired invitation token")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	ledger, err := app.models.Ledgers.GetByID(ledgerID, user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"ledger": ledger.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) readLedger(w http.ResponseWriter, r *http.Request) (*data.Ledger, bool) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return nil, false
+	}
+
+	user := app.contextGetUser(r)
+	ledger, err := app.models.Ledgers.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	return ledger, true
+}
+
+func (app *application) writeLedgerMembers(w http.ResponseWriter, r *http.Request, ledger *data.Ledger) {
+	members, err := app.models.Ledgers.GetMembers(ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"members": ledgerMembersDTO(members)}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) defaultLedger(user *data.User) (*data.Ledger, error) {
+	ledger, err := app.models.Ledgers.GetDefaultForUser(user.ID)
+	if err == nil || !errors.Is(err, data.ErrRecordNotFound) {
+		return ledger, err
+	}
+
+	ledger = &data.Ledger{Name: data.DefaultLedgerName}
+	err = app.models.Ledgers.Insert(ledger, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+func (app *application) resolveWritableLedger(w http.ResponseWriter, r *http.Request, v *validator.Validator, ledger *data.Ledger, user *data.User) (*data.Ledger, bool) {
+	var err error
+
+	if ledger == nil || ledger.ID == 0 {
+		ledger, err = app.defaultLedger(user)
+	} else {
+		ledger, err = app.models.Ledgers.GetByID(ledger.ID, user.ID)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("ledger", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return ledger, true
+}
+
+func (app *application) validateWritableCategory(w http.ResponseWriter, r *http.Request, v *validator.Validator, categoryID int64, userID int64) (*data.Category, bool) {
+	category, err := app.models.Categories.GetByID(categoryID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("category", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return nil, false
+	}
+
+	if !category.Ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return nil, false
+	}
+
+	return category, true
+}
+
+func (app *application) ledgerUser(u *data.User, current *data.User) (*data.User, error) {
+	if u == nil || u.ID == current.ID {
+		return current, nil
+	}
+
+	user, err := app.models.Users.GetByID(u.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			return u, nil
+		default:
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func ledgerMembersDTO(members []*data.LedgerMember) []*data.LedgerMemberDTO {
+	membersDTO := []*data.LedgerMemberDTO{}
+	for _, m := range members {
+		membersDTO = append(membersDTO, m.ToDTO())
+	}
+
+	return membersDTO
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"expvar"
 	"flag"
+	"fmt"
 	"meus_gastos/configuration"
 	"meus_gastos/internal/data"
 	"meus_gastos/internal/jsonlog"
@@ -40,14 +42,25 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	recurring struct {
+		enabled  bool
+		interval time.Duration
+	}
+	exchangeRatesFile string
+	categoryTemplates struct {
+		file        string
+		defaultCode string
+	}
 }
 
 type application struct {
-	config config
-	logger *jsonlog.Logger
-	models data.Models
-	mailer mailer.Mailer
-	wg     sync.WaitGroup
+	config            config
+	logger            *jsonlog.Logger
+	models            data.Models
+	mailer            mailer.Mailer
+	categoryTemplates []*data.CategoryTemplatePack
+	wg                sync.WaitGroup
+	shutdown          chan struct{}
 }
 
 const version = "1.0.0"
@@ -70,6 +83,14 @@ func main() {
 	flag.StringVar(&cfg.smtp.password, "smtp-password", c.Mail.PASSWORD, "SMTP password")
 	flag.StringVar(&cfg.smtp.sender, "smtp-sender", "Greenlight <no-reply@greenlight.alexedwards.net>", "SMTP sender")
 
+	flag.BoolVar(&cfg.recurring.enabled, "recurring-enabled", true, "Enable recurring transactions worker")
+	flag.DurationVar(&cfg.recurring.interval, "recurring-interval", time.Minute, "Recurring transactions worker interval")
+
+	flag.StringVar(&cfg.exchangeRatesFile, "exchange-rates-file", "", "CSV file (base,quote,date,rate) of exchange rates to load on startup")
+
+	flag.StringVar(&cfg.categoryTemplates.file, "category-templates-file", "", "JSON file of category template packs replacing the built-in ones")
+	flag.StringVar(&cfg.categoryTemplates.defaultCode, "default-category-template", data.DefaultCategoryTemplate, "Category template pack seeded when a user is activated (empty to disable)")
+
 	flag.Func("cors-trusted-origins", "Trusted CORS origins (space separated)", func(val string) error {
 		cfg.cors.trustedOrigins = strings.Fields(val)
 		return nil
@@ -79,6 +100,10 @@ func main() {
 
 	logger := jsonlog.New(os.Stdout, jsonlog.LevelInfo)
 
+	if cfg.recurring.enabled && cfg.recurring.interval <= 0 {
+		logger.PrintFatal(errors.New("recurring-interval must be greater than zero"), nil)
+	}
+
 	db, err := openDB(cfg)
 	if err != nil {
 		logger.PrintFatal(err, nil)
@@ -102,10 +127,36 @@ func main() {
 	}))
 
 	app := &application{
-		config: cfg,
-		logger: logger,
-		models: data.NewModels(db),
-		mailer: mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		config:            cfg,
+		logger:            logger,
+		models:            data.NewModels(db),
+		mailer:            mailer.New(cfg.smtp.host, cfg.smtp.port, cfg.smtp.username, cfg.smtp.password, cfg.smtp.sender),
+		categoryTemplates: data.DefaultCategoryTemplates,
+		shutdown:          make(chan struct{}),
+	}
+
+	if cfg.categoryTemplates.file != "" {
+		err = app.loadCategoryTemplatesFile(cfg.categoryTemplates.file)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+	}
+
+	if cfg.categoryTemplates.defaultCode != "" && data.FindCategoryTemplatePack(app.categoryTemplates, cfg.categoryTemplates.defaultCode) == nil {
+		logger.PrintFatal(fmt.Errorf("default category template %q not found", cfg.categoryTemplates.defaultCode), nil)
+	}
+
+	if cfg.exchangeRatesFile != "" {
+		err = app.loadExchangeRatesFile(cfg.exchangeRatesFile)
+		if err != nil {
+			logger.PrintFatal(err, nil)
+		}
+	}
+
+	app.startTokenCleanup()
+
+	if cfg.recurring.enabled {
+		app.startRecurringWorker()
 	}
 
 	err = app.server()
@@ -164,13 +164,24 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 		// 	return
 		// }
 
-		username, err := app.extractUsername(token)
+		claims, err := app.parseToken(token)
 		if err != nil {
 			app.invalidCredentialsResponse(w, r)
 			return
 		}
 
-		user, err := app.models.Users.GetByEmail(username)
+		revoked, err := app.models.Tokens.IsAccessTokenRevoked(claims.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if revoked {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
+		user, err := app.models.Users.GetByID(claims.UserID)
 		if err != nil {
 			switch {
 			case errors.Is(err, data.ErrRecordNotFound):
@@ -181,7 +192,13 @@ func (app *application) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
+		if user.PasswordChangedAt != nil && claims.IssuedAt != nil && claims.IssuedAt.Before(user.PasswordChangedAt.Truncate(time.Second)) {
+			app.invalidAuthenticationTokenResponse(w, r)
+			return
+		}
+
 		r = app.contextSetUser(r, user)
+		r = app.contextSetTokenClaims(r, claims)
 		next.ServeHTTP(w, r)
 	})
 }
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+)
+
+func (app *application) listRecurringTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Description string
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Description = app.readString(qs, "description", "")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "id")
+	input.Filters.SortSafelist = []string{"id", "description", "next_run", "-id", "-description", "-next_run"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	recurring, metadata, err := app.models.Recurring.GetAll(input.Description, user.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	recurringDTO := []*data.RecurringTransactionDTO{}
+	for _, rt := range recurring {
+		err = prepareRecurringTransactionForResponse(app, rt, user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+		recurringDTO = append(recurringDTO, rt.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recurring_transactions": recurringDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createRecurringTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.RecurringTransactionDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	dto.User = user.ToDTO()
+	rt := dto.ToModel()
+
+	v := validator.New()
+
+	if data.ValidateRecurringTransaction(v, rt); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, ok := app.validateWritableCategory(w, r, v, rt.Category.ID, user.ID)
+	if !ok {
+		return
+	}
+
+	err = app.models.Recurring.Insert(rt)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = prepareRecurringTransactionForResponse(app, rt, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/recurring-transactions/%d", rt.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"recurring_transaction": rt.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showRecurringTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rt, err := app.models.Recurring.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareRecurringTransactionForResponse(app, rt, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recurring_transaction": rt.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) updateRecurringTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var dto data.RecurringTransactionDTO
+	err = app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	rt, err := app.models.Recurring.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	frequency, startDate := rt.Frequency, rt.StartDate
+	dto.ToDTOUpdateRecurringTransaction(rt)
+
+	v := validator.New()
+	if rt.OccurrencesGenerated > 0 {
+		v.Check(rt.Frequency == frequency, "frequency", "cannot be changed after occurrences were generated")
+		v.Check(rt.StartDate.Equal(startDate.Time), "start_date", "cannot be changed after occurrences were generated")
+	}
+
+	if data.ValidateRecurringTransaction(v, rt); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if dto.Category != nil {
+		_, ok := app.validateWritableCategory(w, r, v, rt.Category.ID, user.ID)
+		if !ok {
+			return
+		}
+	}
+
+	err = app.models.Recurring.Update(rt, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = prepareRecurringTransactionForResponse(app, rt, user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"recurring_transaction": rt.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteRecurringTransactionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Recurring.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "recurring transaction successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func prepareRecurringTransactionForResponse(app *application, rt *data.RecurringTransaction, user *data.User) error {
+	rt.User = user
+
+	category, err := app.models.Categories.GetByID(rt.Category.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	if category != nil {
+		rt.Category = category
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+)
+
+func (app *application) summaryReportHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	startDate := app.readDate(qs, "start", "2006-01-02")
+	endDate := app.readDate(qs, "end", "2006-01-02")
+
+	if startDate != nil && endDate != nil {
+		v.Check(!endDate.Before(*startDate), "end", "must not be before start")
+	}
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	summary, err := app.models.Reports.Summary(user.ID, startDate, endDate)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"summary": summary}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) timeSeriesReportHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	startDate := app.readDate(qs, "start", "2006-01-02")
+	endDate := app.readDate(qs, "end", "2006-01-02")
+	granularity := app.readString(qs, "granularity", "month")
+	groupBy := app.readString(qs, "group_by", "none")
+
+	categoryType := data.DESPESA
+	if groupBy == "category_type" {
+		categoryType = 0
+	}
+	if categoryStr := app.readString(qs, "category_type", ""); categoryStr != "" {
+		categoryType = data.TypeCategoriaFromString(categoryStr)
+		v.Check(categoryType != 0, "category_type", "must be RECEITA or DESPESA")
+	}
+
+	if data.ValidateTimeSeries(v, granularity, groupBy, startDate, endDate); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	series, err := app.models.Reports.TimeSeries(user.ID, *startDate, *endDate, granularity, groupBy, categoryType)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := envelope{
+		"granularity": granularity,
+		"group_by":    groupBy,
+		"currency":    user.Currency,
+		"series":      series,
+	}
+
+	if categoryType != 0 {
+		env["category_type"] = categoryType.String()
+	}
+
+	err = app.writeJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
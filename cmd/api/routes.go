@@ -16,21 +16,110 @@ func (app *application) routes() http.Handler {
 
 	router.HandlerFunc(http.MethodPost, "/v1/users", app.registerUserHandler)
 	router.HandlerFunc(http.MethodPut, "/v1/users/activated", app.activateUserHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/users/activation-code", app.resendActivationCodHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/users/password-reset", app.requestPasswordResetHandler)
+	router.HandlerFunc(http.MethodPut, "/v1/users/password", app.resetPasswordHandler)
+	router.HandlerFunc(http.MethodGet, "/v1/users/me", app.requireAuthenticatedUser(app.showCurrentUserHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me", app.requireAuthenticatedUser(app.updateCurrentUserHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/users/me", app.requireAuthenticatedUser(app.deleteCurrentUserHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/users/me/password", app.requireAuthenticatedUser(app.changePasswordHandler))
 
 	router.HandlerFunc(http.MethodGet, "/v1/categories", app.requireActivatedUser(app.listCategoriesHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/categories", app.requireActivatedUser(app.createCategoryHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/categories/:id", app.requireActivatedUser(app.showCategoryHandler))
 	router.HandlerFunc(http.MethodPut, "/v1/categories/:id", app.requireActivatedUser(app.updateCategoryHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/categories/:id", app.requireActivatedUser(app.deleteCategoryHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/category-tree", app.requireActivatedUser(app.showCategoryTreeHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categorization-rules", app.requireActivatedUser(app.listCategorizationRulesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/categorization-rules", app.requireActivatedUser(app.createCategorizationRuleHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/categorization-rules/apply", app.requireActivatedUser(app.applyCategorizationRulesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/categorization-rules/:id", app.requireActivatedUser(app.showCategorizationRuleHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/categorization-rules/:id", app.requireActivatedUser(app.updateCategorizationRuleHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/categorization-rules/:id", app.requireActivatedUser(app.deleteCategorizationRuleHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/category-templates", app.requireActivatedUser(app.listCategoryTemplatesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/category-templates/apply", app.requireActivatedUser(app.applyCategoryTemplateHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/ledgers", app.requireActivatedUser(app.listLedgersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/ledgers", app.requireActivatedUser(app.createLedgerHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/ledgers/:id", app.requireActivatedUser(app.showLedgerHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/ledgers/:id", app.requireActivatedUser(app.updateLedgerHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/ledgers/:id/members", app.requireActivatedUser(app.listLedgerMembersHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/ledgers/:id/members/:user_id", app.requireActivatedUser(app.updateLedgerMemberHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/ledgers/:id/members/:user_id", app.requireActivatedUser(app.removeLedgerMemberHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/ledgers/:id/invitations", app.requireActivatedUser(app.listLedgerInvitationsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/ledgers/:id/invitations", app.requireActivatedUser(app.createLedgerInvitationHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/ledgers/:id/invitations/:invitation_id", app.requireActivatedUser(app.deleteLedgerInvitationHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/ledgers/:id/balances", app.requireActivatedUser(app.listLedgerBalancesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/ledgers/:id/settlements", app.requireActivatedUser(app.listSettlementsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/ledgers/:id/settlements", app.requireActivatedUser(app.createSettlementHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/ledgers/:id/settlements/:settlement_id", app.requireActivatedUser(app.deleteSettlementHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/ledger-invitations/accept", app.requireActivatedUser(app.acceptLedgerInvitationHandler))
 
 	router.HandlerFunc(http.MethodGet, "/v1/transactions", app.requireActivatedUser(app.listTransactionsHandler))
 	router.HandlerFunc(http.MethodPost, "/v1/transactions", app.requireActivatedUser(app.createTransactionHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/transactions/find/:id", app.requireActivatedUser(app.showTransactionHandler))
 	router.HandlerFunc(http.MethodPut, "/v1/transactions/update/:id", app.requireActivatedUser(app.updateTransactionHandler))
 	router.HandlerFunc(http.MethodDelete, "/v1/transactions/delete/:id", app.requireActivatedUser(app.deleteTransactionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/transactions/splits/:id", app.requireActivatedUser(app.showTransactionSplitsHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/transactions/splits/:id", app.requireActivatedUser(app.updateTransactionSplitsHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/transactions/splits/:id", app.requireActivatedUser(app.deleteTransactionSplitsHandler))
 	router.HandlerFunc(http.MethodGet, "/v1/transactions/category/:id", app.requireActivatedUser(app.listTransactionsByCategoryIDHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/transactions/import/csv", app.requireActivatedUser(app.importCSVTransactionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/transactions/import/ofx", app.requireActivatedUser(app.importOFXTransactionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/transactions/export", app.requireActivatedUser(app.exportTransactionsHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/transactions/suggest-category", app.requireActivatedUser(app.suggestCategoryHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/transactions/batch", app.requireActivatedUser(app.batchTransactionsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/tags", app.requireActivatedUser(app.listTagsHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/accounts", app.requireActivatedUser(app.listAccountsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/accounts", app.requireActivatedUser(app.createAccountHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/:id", app.requireActivatedUser(app.showAccountHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/accounts/:id", app.requireActivatedUser(app.updateAccountHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/accounts/:id", app.requireActivatedUser(app.deleteAccountHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/:id/entries", app.requireActivatedUser(app.listAccountEntriesHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/accounts/:id/invoices", app.requireActivatedUser(app.listAccountInvoicesHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/installment-purchases", app.requireActivatedUser(app.listInstallmentPurchasesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/installment-purchases", app.requireActivatedUser(app.createInstallmentPurchaseHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/installment-purchases/:id", app.requireActivatedUser(app.showInstallmentPurchaseHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/installment-purchases/:id", app.requireActivatedUser(app.deleteInstallmentPurchaseHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/transfers", app.requireActivatedUser(app.listTransfersHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/transfers", app.requireActivatedUser(app.createTransferHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/transfers/:id", app.requireActivatedUser(app.showTransferHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/transfers/:id", app.requireActivatedUser(app.deleteTransferHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/recurring-transactions", app.requireActivatedUser(app.listRecurringTransactionsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/recurring-transactions", app.requireActivatedUser(app.createRecurringTransactionHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/recurring-transactions/:id", app.requireActivatedUser(app.showRecurringTransactionHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/recurring-transactions/:id", app.requireActivatedUser(app.updateRecurringTransactionHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/recurring-transactions/:id", app.requireActivatedUser(app.deleteRecurringTransactionHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/budgets", app.requireActivatedUser(app.listBudgetsHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/budgets", app.requireActivatedUser(app.createBudgetHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/budgets/:id", app.requireActivatedUser(app.showBudgetHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/budgets/:id", app.requireActivatedUser(app.updateBudgetHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/budgets/:id", app.requireActivatedUser(app.deleteBudgetHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/reports/budgets", app.requirePermission("reports:read", app.budgetStatusHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/reports/summary", app.requirePermission("reports:read", app.summaryReportHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/reports/timeseries", app.requirePermission("reports:read", app.timeSeriesReportHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/exchange-rates", app.requireActivatedUser(app.listExchangeRatesHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/exchange-rates", app.requirePermission("exchange_rates:write", app.loadExchangeRatesHandler))
+
+	router.HandlerFunc(http.MethodGet, "/v1/admin/users", app.requirePermission("users:admin", app.listUsersAdminHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/admin/users/:id/activate", app.requirePermission("users:admin", app.activateUserAdminHandler))
+	router.HandlerFunc(http.MethodPut, "/v1/admin/users/:id/deactivate", app.requirePermission("users:admin", app.deactivateUserAdminHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/users/:id/permissions", app.requirePermission("users:admin", app.listUserPermissionsAdminHandler))
+	router.HandlerFunc(http.MethodPost, "/v1/admin/users/:id/permissions", app.requirePermission("users:admin", app.grantUserPermissionsAdminHandler))
+	router.HandlerFunc(http.MethodDelete, "/v1/admin/users/:id/permissions/:code", app.requirePermission("users:admin", app.revokeUserPermissionAdminHandler))
+	router.HandlerFunc(http.MethodGet, "/v1/admin/permissions", app.requirePermission("users:admin", app.listPermissionsAdminHandler))
 
 	router.HandlerFunc(http.MethodPost, "/v1/tokens/authentication", app.createAuthenticationTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/refresh", app.refreshTokenHandler)
+	router.HandlerFunc(http.MethodPost, "/v1/tokens/logout", app.requireAuthenticatedUser(app.logoutHandler))
 
 	router.Handler(http.MethodGet, "/debug/vars", expvar.Handler())
 
@@ -47,6 +47,7 @@ func (app *application) server() error {
 			"addr": srv.Addr,
 		})
 
+		close(app.shutdown)
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
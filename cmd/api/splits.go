@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"strings"
+)
+
+func (app *application) showTransactionSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	transaction, err := app.models.Transactions.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeTransactionSplits(w, r, transaction)
+}
+
+func (app *application) updateTransactionSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		Splits []struct {
+			UserID int64       `json:"user_id"`
+			Amount *data.Money `json:"amount"`
+		} `json:"splits"`
+	}
+
+	err = app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	transaction, err := app.models.Transactions.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	category, ok := app.validateWritableCategory(w, r, v, transaction.Category.ID, user.ID)
+	if !ok {
+		return
+	}
+
+	if category.Type != data.DESPESA {
+		v.AddError("category", "only DESPESA transactions can be split")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	members, err := app.models.Ledgers.GetMembers(category.Ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	isMember := make(map[int64]bool, len(members))
+	for _, m := range members {
+		isMember[m.User.ID] = true
+	}
+
+	withAmount := 0
+	splits := []*data.Split{}
+	for _, s := range input.Splits {
+		v.Check(isMember[s.UserID], "splits", "must only contain members of the ledger")
+
+		split := &data.Split{User: &data.User{ID: s.UserID}}
+		if s.Amount != nil {
+			split.Amount = *s.Amount
+			withAmount++
+		}
+		splits = append(splits, split)
+	}
+
+	if withAmount == 0 {
+		for i, share := range data.SplitEqually(transaction.Amount, len(splits)) {
+			splits[i].Amount = share
+		}
+	}
+
+	v.Check(withAmount == 0 || withAmount == len(splits), "splits", "must provide an amount for every share or for none")
+
+	if data.ValidateSplits(v, transaction.Amount, splits); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Splits.Replace(transaction.ID, splits)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	app.writeTransactionSplits(w, r, transaction)
+}
+
+func (app *application) deleteTransactionSplitsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	transaction, err := app.models.Transactions.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	if _, ok := app.validateWritableCategory(w, r, v, transaction.Category.ID, user.ID); !ok {
+		return
+	}
+
+	err = app.models.Splits.Replace(transaction.ID, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "transaction splits successfully removed"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listLedgerBalancesHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	balances, err := app.models.Splits.GetBalances(ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"balances": balances}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) listSettlementsHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-occurred_on")
+	input.Filters.SortSafelist = []string{"id", "amount", "occurred_on", "-id", "-amount", "-occurred_on"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	settlements, metadata, err := app.models.Settlements.GetAll(ledger.ID, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	settlementsDTO := []*data.SettlementDTO{}
+	for _, s := range settlements {
+		settlementsDTO = append(settlementsDTO, s.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"settlements": settlementsDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	var input struct {
+		FromUserID *int64      `json:"from_user_id"`
+		ToUserID   int64       `json:"to_user_id"`
+		Amount     *data.Money `json:"amount"`
+		Currency   *string     `json:"currency"`
+		OccurredOn *data.Date  `json:"occurred_on"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	settlement := &data.Settlement{
+		LedgerID:  ledger.ID,
+		From:      &data.User{ID: user.ID},
+		To:        &data.User{ID: input.ToUserID},
+		Currency:  user.Currency,
+		CreatedBy: user.ID,
+	}
+
+	if input.FromUserID != nil {
+		settlement.From.ID = *input.FromUserID
+	}
+
+	if input.Currency != nil {
+		settlement.Currency = strings.ToUpper(*input.Currency)
+	}
+
+	if input.OccurredOn != nil {
+		settlement.OccurredOn = *input.OccurredOn
+	}
+
+	v := validator.New()
+	v.Check(settlement.From.ID == user.ID || settlement.To.ID == user.ID, "from_user_id", "you must be the payer or the receiver")
+
+	balances, err := app.models.Splits.GetBalances(ledger.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	var owed data.Money
+	if b := data.OutstandingBalance(balances, settlement.From.ID, settlement.To.ID, settlement.Currency); b != nil {
+		owed = b.Amount
+		settlement.From = b.From.ToModel()
+		settlement.To = b.To.ToModel()
+	}
+
+	v.Check(owed > 0, "to_user_id", "there is no outstanding balance between these users in this currency")
+
+	settlement.Amount = owed
+	if input.Amount != nil {
+		settlement.Amount = *input.Amount
+		v.Check(settlement.Amount <= owed, "amount", fmt.Sprintf("must not exceed the outstanding balance of %s", owed))
+	}
+
+	if data.ValidateSettlement(v, settlement); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.models.Settlements.Insert(settlement)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrSettlementExceedsBalance):
+			app.editConflictResponse(w, r)
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/ledgers/%d/settlements", ledger.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"settlement": settlement.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteSettlementHandler(w http.ResponseWriter, r *http.Request) {
+	ledger, ok := app.readLedger(w, r)
+	if !ok {
+		return
+	}
+
+	if !ledger.Role.CanWrite() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	settlementID, err := app.readIntParam(r, "settlement_id")
+	if err != nil {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	settlement, err := app.models.Settlements.GetByID(settlementID, ledger.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if settlement.CreatedBy != app.contextGetUser(r).ID && !ledger.Role.CanManage() {
+		app.notPermittedResponse(w, r)
+		return
+	}
+
+	err = app.models.Settlements.Delete(settlement.ID, ledger.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "settlement successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) writeTransactionSplits(w http.ResponseWriter, r *http.Request, transaction *data.Transaction) {
+	splits, err := app.models.Splits.GetForTransaction(transaction.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	splitsDTO := []*data.SplitDTO{}
+	for _, s := range splits {
+		splitsDTO = append(splitsDTO, s.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{
+		"transaction_id": transaction.ID,
+		"amount":         transaction.Amount,
+		"currency":       transaction.Currency,
+		"paid_by":        transaction.User.ID,
+		"splits":         splitsDTO,
+	}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
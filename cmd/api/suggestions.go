@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"slices"
+)
+
+func (app *application) suggestCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Description  string
+		Amount       data.Money
+		AccountID    int64
+		LedgerID     int64
+		CategoryType data.TypeCategoria
+		Limit        int
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.Description = app.readString(qs, "description", "")
+	input.AccountID = int64(app.readInt(qs, "account_id", 0, v))
+	input.LedgerID = int64(app.readInt(qs, "ledger_id", 0, v))
+	input.Limit = app.readInt(qs, "limit", 3, v)
+
+	if amount := app.readString(qs, "amount", ""); amount != "" {
+		parsed, err := data.ParseMoney(amount)
+		if err != nil {
+			v.AddError("amount", "must be a decimal number")
+		}
+		input.Amount = parsed.Abs()
+	}
+
+	if categoryType := app.readString(qs, "type", ""); categoryType != "" {
+		input.CategoryType = data.TypeCategoriaFromString(categoryType)
+		v.Check(input.CategoryType != 0, "type", "must be RECEITA or DESPESA")
+	}
+
+	v.Check(input.Description != "", "description", "must be provided")
+	v.Check(len(input.Description) <= 500, "description", "must not be more than 500 bytes long")
+	v.Check(input.Limit >= 1 && input.Limit <= 10, "limit", "must be between 1 and 10")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	suggestions := []*data.CategorySuggestion{}
+
+	rules, err := app.models.Rules.GetActive(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	transaction := &data.Transaction{Description: input.Description, Amount: input.Amount}
+	if input.AccountID != 0 {
+		transaction.Account = &data.Account{ID: input.AccountID}
+	}
+
+	rules = slices.DeleteFunc(rules, func(rule *data.CategorizationRule) bool {
+		return input.LedgerID != 0 && rule.Category.Ledger.ID != input.LedgerID
+	})
+
+	if rule := data.MatchCategorizationRule(rules, transaction, input.CategoryType); rule != nil {
+		category, err := app.models.Categories.GetByID(rule.Category.ID, user.ID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if category != nil {
+			category.User = nil
+			suggestions = append(suggestions, &data.CategorySuggestion{
+				Category:   category.ToDTO(),
+				Confidence: 1,
+				Source:     data.SuggestionSourceRule,
+				RuleID:     rule.ID,
+			})
+		}
+	}
+
+	history, err := app.models.Transactions.SuggestCategories(input.Description, user.ID, input.LedgerID, input.CategoryType, input.Limit)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	for _, s := range history {
+		if len(suggestions) == input.Limit {
+			break
+		}
+
+		if len(suggestions) > 0 && *suggestions[0].Category.ID == *s.Category.ID {
+			suggestions[0].Transactions = s.Transactions
+			suggestions[0].LastUsed = s.LastUsed
+			continue
+		}
+
+		suggestions = append(suggestions, s)
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"description": input.Description, "suggestions": suggestions}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"meus_gastos/internal/validator"
+	"net/http"
+)
+
+func (app *application) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	qs := r.URL.Query()
+	ledgerID := int64(app.readInt(qs, "ledger_id", 0, v))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	tags, err := app.models.Tags.GetAll(user.ID, ledgerID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"tags": tags}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"meus_gastos/configuration"
 	"meus_gastos/internal/data"
 	"meus_gastos/internal/validator"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -14,6 +17,17 @@ import (
 var c = configuration.New()
 var secretKey = []byte(c.Security.SecretKey)
 
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+type tokenClaims struct {
+	Username string `json:"username"`
+	UserID   int64  `json:"-"`
+	jwt.RegisteredClaims
+}
+
 func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email    string `json:"email"`
@@ -58,56 +72,188 @@ func (app *application) createAuthenticationTokenHandler(w http.ResponseWriter,
 		return
 	}
 
-	token, err := createToken(user.Email)
+	env, err := app.issueTokens(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateTokenPlaintext(v, input.RefreshToken); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	userID, err := app.models.Tokens.Rotate(input.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		case errors.Is(err, data.ErrTokenRevoked):
+			err = app.models.Tokens.RevokeAllForUser(userID)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	user, err := app.models.Users.GetByID(userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	env, err := app.issueTokens(user)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+		All          bool   `json:"all"`
+	}
+
+	if r.ContentLength != 0 {
+		err := app.readJSON(w, r, &input)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	user := app.contextGetUser(r)
+	claims := app.contextGetTokenClaims(r)
+
+	err := app.models.Tokens.RevokeAccessToken(claims.ID, claims.ExpiresAt.Time)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	switch {
+	case input.All:
+		err = app.models.Tokens.RevokeAllForUser(user.ID)
+	case input.RefreshToken != "":
+		err = app.models.Tokens.RevokeRefreshToken(input.RefreshToken, user.ID)
+	}
+
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"authentication_token": token}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "successfully logged out"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
 }
 
-func createToken(username string) (string, error) {
+func (app *application) issueTokens(user *data.User) (envelope, error) {
+	token, expiry, err := createToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := app.models.Tokens.NewRefreshToken(user.ID, refreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	env := envelope{
+		"authentication_token": token,
+		"expiry":               expiry,
+		"refresh_token":        refreshToken,
+	}
+
+	return env, nil
+}
+
+func createToken(user *data.User) (string, time.Time, error) {
+	jti := make([]byte, 16)
+
+	_, err := rand.Read(jti)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiry := now.Add(accessTokenTTL)
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256,
-		jwt.MapClaims{
-			"username": username,
-			"exp":      time.Now().Add(time.Hour * 24).Unix(),
+		tokenClaims{
+			Username: user.Email,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        hex.EncodeToString(jti),
+				Subject:   strconv.FormatInt(user.ID, 10),
+				IssuedAt:  jwt.NewNumericDate(now),
+				ExpiresAt: jwt.NewNumericDate(expiry),
+			},
 		})
 	tokenString, err := token.SignedString(secretKey)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 
-	return tokenString, nil
+	return tokenString, expiry, nil
 }
 
-func (app *application) extractUsername(tokenString string) (string, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+func (app *application) parseToken(tokenString string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
 		return secretKey, nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
 
 	if err != nil {
-		return "", err
-	}
-
-	if !token.Valid {
-		return "", nil
+		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return "", nil
+	if !token.Valid || claims.Username == "" || claims.ID == "" {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
-	username, ok := claims["username"].(string)
-	if !ok {
-		return "", nil
+	claims.UserID, err = strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil || claims.UserID < 1 {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
-	return username, nil
+	return claims, nil
 }
 
 func (app *application) verifyToken(tokenString string) (bool, error) {
@@ -32,8 +32,8 @@ func (app *application) listTransactionsByCategoryIDHandler(w http.ResponseWrite
 	input.EndDate = app.readDate(qs, "end", "2006-01-02")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "description", "-id", "-description"}
+	input.Filters.Sort = app.readString(qs, "sort", "occurred_on")
+	input.Filters.SortSafelist = []string{"id", "description", "occurred_on", "-id", "-description", "-occurred_on"}
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -69,6 +69,10 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 		Name string
 		data.Filters
 		CategoryType data.TypeCategoria
+		AccountID    int64
+		LedgerID     int64
+		Tags         []string
+		TagsMatch    string
 		StartDate    *time.Time
 		EndDate      *time.Time
 	}
@@ -80,13 +84,19 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 	if categoryStr != "" {
 		input.CategoryType = data.TypeCategoriaFromString(categoryStr)
 	}
+	input.AccountID = int64(app.readInt(qs, "account_id", 0, v))
+	input.LedgerID = int64(app.readInt(qs, "ledger_id", 0, v))
+	input.Tags = data.NormalizeTags(app.readCSV(qs, "tags", []string{}))
+	input.TagsMatch = app.readString(qs, "tags_match", data.TagsMatchAny)
 	input.StartDate = app.readDate(qs, "start", "2006-01-02")
 	input.EndDate = app.readDate(qs, "end", "2006-01-02")
 	input.Name = app.readString(qs, "description", "")
 	input.Filters.Page = app.readInt(qs, "page", 1, v)
 	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
-	input.Filters.Sort = app.readString(qs, "sort", "id")
-	input.Filters.SortSafelist = []string{"id", "description", "-id", "-description"}
+	input.Filters.Sort = app.readString(qs, "sort", "occurred_on")
+	input.Filters.SortSafelist = []string{"id", "description", "occurred_on", "-id", "-description", "-occurred_on"}
+
+	v.Check(validator.In(input.TagsMatch, data.TagsMatchAny, data.TagsMatchAll), "tags_match", "must be any or all")
 
 	if data.ValidateFilters(v, input.Filters); !v.Valid() {
 		app.failedValidationResponse(w, r, v.Errors)
@@ -99,6 +109,10 @@ func (app *application) listTransactionsHandler(w http.ResponseWriter, r *http.R
 		input.StartDate,
 		input.EndDate,
 		input.CategoryType,
+		input.AccountID,
+		input.LedgerID,
+		input.Tags,
+		input.TagsMatch,
 		input.Filters,
 	)
 
@@ -136,6 +150,16 @@ func (app *application) createTransactionHandler(w http.ResponseWriter, r *http.
 	dto.User = user.ToDTO()
 	transaction := dto.ToModel()
 
+	if transaction.Category == nil {
+		rules, err := app.models.Rules.GetActive(user.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		app.categorizeTransaction(rules, transaction, 0)
+	}
+
 	v := validator.New()
 
 	if data.ValidateTransaction(v, transaction); !v.Valid() {
@@ -148,6 +172,16 @@ func (app *application) createTransactionHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	category, ok := app.validateWritableCategory(w, r, v, transaction.Category.ID, user.ID)
+	if !ok {
+		return
+	}
+	transaction.Category = category
+
+	if transaction.Account != nil && !app.validateTransactionAccount(w, r, v, transaction, user.ID) {
+		return
+	}
+
 	err = app.models.Transactions.Insert(transaction)
 
 	if err != nil {
@@ -236,7 +270,47 @@ func (app *application) updateTransactionHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	current, ok := app.validateWritableCategory(w, r, v, transaction.Category.ID, user.ID)
+	if !ok {
+		return
+	}
+
+	owner := transaction.User
 	dto.ToDTOUpdateTransaction(transaction)
+	transaction.User = owner
+
+	if dto.Category != nil {
+		category, ok := app.validateWritableCategory(w, r, v, transaction.Category.ID, user.ID)
+		if !ok {
+			return
+		}
+		transaction.Category = category
+	}
+
+	if (dto.Account != nil || dto.Currency != nil) && transaction.Account != nil && !app.validateTransactionAccount(w, r, v, transaction, user.ID) {
+		return
+	}
+
+	if dto.Amount != nil || transaction.Category.ID != current.ID {
+		splits, err := app.models.Splits.GetForTransaction(transaction.ID)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if len(splits) > 0 && data.SplitsTotal(splits) != transaction.Amount {
+			v.AddError("amount", "must equal the sum of the transaction splits, update the splits first")
+		}
+
+		if len(splits) > 0 && !data.CanMoveSplitTransaction(current, transaction.Category) {
+			v.AddError("category", "the transaction is split and can only move to a DESPESA category of the same ledger")
+		}
+
+		if !v.Valid() {
+			app.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+	}
 
 	err = app.models.Transactions.Update(transaction, user.ID)
 	if err != nil {
@@ -268,7 +342,24 @@ func (app *application) deleteTransactionHandler(w http.ResponseWriter, r *http.
 	}
 
 	user := app.contextGetUser(r)
-	err = app.models.Transactions.Delete(id, user.ID)
+	transaction, err := app.models.Transactions.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	v := validator.New()
+	_, ok := app.validateWritableCategory(w, r, v, transaction.Category.ID, user.ID)
+	if !ok {
+		return
+	}
+
+	err = app.models.Transactions.Delete(transaction.ID, user.ID)
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrRecordNotFound):
@@ -286,7 +377,11 @@ func (app *application) deleteTransactionHandler(w http.ResponseWriter, r *http.
 }
 
 func prepareTransactionForResponse(app *application, transaction *data.Transaction, user *data.User) error {
-	transaction.User = user
+	owner, err := app.ledgerUser(transaction.User, user)
+	if err != nil {
+		return err
+	}
+	transaction.User = owner
 
 	category, err := app.models.Categories.GetByID(transaction.Category.ID, user.ID)
 
@@ -298,5 +393,16 @@ func prepareTransactionForResponse(app *application, transaction *data.Transacti
 		transaction.Category = category
 	}
 
+	if transaction.Account != nil && transaction.Account.Name == "" {
+		account, err := app.models.Accounts.GetByID(transaction.Account.ID, user.ID)
+		if err != nil && !errors.Is(err, data.ErrRecordNotFound) {
+			return err
+		}
+
+		if account != nil {
+			transaction.Account = account
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+)
+
+type batchOperationInput struct {
+	Op            string               `json:"op"`
+	TransactionID int64                `json:"transaction_id"`
+	Version       int                  `json:"version"`
+	Transaction   *data.TransactionDTO `json:"transaction"`
+}
+
+type transactionBatch struct {
+	user       *data.User
+	categories map[int64]*data.Category
+	accounts   map[int64]*data.Account
+	rules      []*data.CategorizationRule
+	seen       map[int64]bool
+}
+
+func (app *application) batchTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Operations []*batchOperationInput `json:"operations"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+	v.Check(len(input.Operations) > 0, "operations", "must contain at least one operation")
+	v.Check(len(input.Operations) <= data.MaxBatchOperations, "operations", fmt.Sprintf("must not contain more than %d operations", data.MaxBatchOperations))
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	batch := &transactionBatch{
+		user:       app.contextGetUser(r),
+		categories: map[int64]*data.Category{},
+		accounts:   map[int64]*data.Account{},
+		seen:       map[int64]bool{},
+	}
+
+	operations := make([]*data.TransactionOperation, len(input.Operations))
+	results := make([]*data.TransactionOperationResult, len(input.Operations))
+	invalid := false
+
+	for i, item := range input.Operations {
+		if item == nil {
+			item = &batchOperationInput{}
+		}
+
+		results[i] = &data.TransactionOperationResult{Index: i, Op: item.Op, TransactionID: item.TransactionID}
+
+		ev := validator.New()
+		operation, err := app.prepareBatchOperation(batch, item, ev)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			results[i].Status = data.BatchStatusNotFound
+			invalid = true
+		case err != nil:
+			app.serverErrorResponse(w, r, err)
+			return
+		case !ev.Valid():
+			results[i].Status = data.BatchStatusInvalid
+			results[i].Errors = ev.Errors
+			invalid = true
+		default:
+			operations[i] = operation
+		}
+	}
+
+	if invalid {
+		for _, result := range results {
+			if result.Status == "" {
+				result.Status = data.BatchStatusSkipped
+			}
+		}
+
+		app.errorResponse(w, r, http.StatusUnprocessableEntity, envelope{"results": results})
+		return
+	}
+
+	err = app.models.Transactions.ExecBatch(operations, results, batch.user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.errorResponse(w, r, http.StatusConflict, envelope{"results": results})
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"results": results}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) prepareBatchOperation(batch *transactionBatch, item *batchOperationInput, v *validator.Validator) (*data.TransactionOperation, error) {
+	switch item.Op {
+	case data.OpCreate:
+		return app.prepareBatchCreate(batch, item, v)
+	case data.OpUpdate, data.OpDelete:
+		return app.prepareBatchChange(batch, item, v)
+	default:
+		v.AddError("op", "must be one of create, update or delete")
+		return nil, nil
+	}
+}
+
+func (app *application) prepareBatchCreate(batch *transactionBatch, item *batchOperationInput, v *validator.Validator) (*data.TransactionOperation, error) {
+	if v.Check(item.Transaction != nil, "transaction", "must be provided"); !v.Valid() {
+		return nil, nil
+	}
+
+	transaction := item.Transaction.ToModel()
+	transaction.ID = 0
+	transaction.Version = 0
+	transaction.User = batch.user
+
+	if transaction.Category == nil {
+		if batch.rules == nil {
+			rules, err := app.models.Rules.GetActive(batch.user.ID)
+			if err != nil {
+				return nil, err
+			}
+			batch.rules = rules
+		}
+
+		app.categorizeTransaction(batch.rules, transaction, 0)
+	}
+
+	if data.ValidateTransaction(v, transaction); !v.Valid() {
+		return nil, nil
+	}
+
+	err := app.prepareBatchReferences(batch, transaction, true, v)
+	if err != nil || !v.Valid() {
+		return nil, err
+	}
+
+	return &data.TransactionOperation{Op: data.OpCreate, Transaction: transaction}, nil
+}
+
+func (app *application) prepareBatchChange(batch *transactionBatch, item *batchOperationInput, v *validator.Validator) (*data.TransactionOperation, error) {
+	id := item.TransactionID
+	version := item.Version
+	if item.Transaction != nil {
+		if id == 0 && item.Transaction.ID != nil {
+			id = *item.Transaction.ID
+		}
+		if version == 0 && item.Transaction.Version != nil {
+			version = *item.Transaction.Version
+		}
+	}
+
+	v.Check(id > 0, "transaction_id", "must be provided")
+	v.Check(version > 0, "version", "must be provided")
+	v.Check(!batch.seen[id], "transaction_id", "must not appear more than once in the batch")
+	if item.Op == data.OpUpdate {
+		v.Check(item.Transaction != nil, "transaction", "must be provided")
+	}
+
+	if !v.Valid() {
+		return nil, nil
+	}
+	batch.seen[id] = true
+
+	transaction, err := app.models.Transactions.GetByID(id, batch.user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := app.batchCategory(batch, transaction.Category.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.Check(category.Ledger.Role.CanWrite(), "transaction_id", "you do not have write access to this transaction"); !v.Valid() {
+		return nil, nil
+	}
+
+	if item.Op == data.OpDelete {
+		transaction.Version = version
+		return &data.TransactionOperation{Op: data.OpDelete, Transaction: transaction}, nil
+	}
+
+	dto := item.Transaction
+	owner := transaction.User
+	dto.ToDTOUpdateTransaction(transaction)
+	transaction.ID = id
+	transaction.Version = version
+	transaction.User = owner
+
+	if data.ValidateTransaction(v, transaction); !v.Valid() {
+		return nil, nil
+	}
+
+	if dto.Category == nil {
+		transaction.Category = category
+	}
+
+	err = app.prepareBatchReferences(batch, transaction, dto.Account != nil || dto.Currency != nil, v)
+	if err != nil || !v.Valid() {
+		return nil, err
+	}
+
+	if dto.Amount != nil || transaction.Category.ID != category.ID {
+		splits, err := app.models.Splits.GetForTransaction(transaction.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(splits) > 0 && data.SplitsTotal(splits) != transaction.Amount {
+			v.AddError("amount", "must equal the sum of the transaction splits, update the splits first")
+		}
+
+		if len(splits) > 0 && !data.CanMoveSplitTransaction(category, transaction.Category) {
+			v.AddError("category", "the transaction is split and can only move to a DESPESA category of the same ledger")
+		}
+
+		if !v.Valid() {
+			return nil, nil
+		}
+	}
+
+	return &data.TransactionOperation{Op: data.OpUpdate, Transaction: transaction}, nil
+}
+
+func (app *application) prepareBatchReferences(batch *transactionBatch, transaction *data.Transaction, checkAccount bool, v *validator.Validator) error {
+	category, err := app.batchCategory(batch, transaction.Category.ID)
+	switch {
+	case errors.Is(err, data.ErrRecordNotFound):
+		v.AddError("category", "does not exist")
+		return nil
+	case err != nil:
+		return err
+	}
+
+	if v.Check(category.Ledger.Role.CanWrite(), "category", "you do not have write access to this category"); !v.Valid() {
+		return nil
+	}
+	transaction.Category = category
+
+	if !checkAccount || transaction.Account == nil {
+		return nil
+	}
+
+	account, ok := batch.accounts[transaction.Account.ID]
+	if !ok {
+		account, err = app.models.Accounts.GetByID(transaction.Account.ID, batch.user.ID)
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("account", "does not exist")
+			return nil
+		case err != nil:
+			return err
+		}
+		batch.accounts[account.ID] = account
+	}
+
+	if transaction.Currency == "" {
+		transaction.Currency = account.Currency
+	}
+
+	v.Check(transaction.Currency == account.Currency, "currency", "must match the account currency")
+	transaction.Account = account
+
+	return nil
+}
+
+func (app *application) batchCategory(batch *transactionBatch, id int64) (*data.Category, error) {
+	if category, ok := batch.categories[id]; ok {
+		return category, nil
+	}
+
+	category, err := app.models.Categories.GetByID(id, batch.user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	batch.categories[id] = category
+	return category, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"meus_gastos/internal/data"
+	"meus_gastos/internal/validator"
+	"net/http"
+	"time"
+)
+
+const defaultTransferDescription = "Transferência"
+
+func (app *application) listTransfersHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		AccountID int64
+		StartDate *time.Time
+		EndDate   *time.Time
+		data.Filters
+	}
+
+	v := validator.New()
+
+	qs := r.URL.Query()
+	input.AccountID = int64(app.readInt(qs, "account_id", 0, v))
+	input.StartDate = app.readDate(qs, "start", "2006-01-02")
+	input.EndDate = app.readDate(qs, "end", "2006-01-02")
+	input.Filters.Page = app.readInt(qs, "page", 1, v)
+	input.Filters.PageSize = app.readInt(qs, "page_size", 20, v)
+	input.Filters.Sort = app.readString(qs, "sort", "-occurred_on")
+	input.Filters.SortSafelist = []string{"id", "amount", "occurred_on", "-id", "-amount", "-occurred_on"}
+
+	if data.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	transfers, metadata, err := app.models.Transfers.GetAll(user.ID, input.AccountID, input.StartDate, input.EndDate, input.Filters)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	transfersDTO := []*data.TransferDTO{}
+	for _, t := range transfers {
+		transfersDTO = append(transfersDTO, t.ToDTO())
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"transfers": transfersDTO, "metadata": metadata}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) createTransferHandler(w http.ResponseWriter, r *http.Request) {
+	var dto data.TransferDTO
+	err := app.readJSON(w, r, &dto)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	transfer := dto.ToModel()
+	transfer.User = user
+
+	if transfer.Description == "" {
+		transfer.Description = defaultTransferDescription
+	}
+
+	v := validator.New()
+
+	if data.ValidateTransfer(v, transfer); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	from, err := app.models.Accounts.GetByID(transfer.FromAccount.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("from_account", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	to, err := app.models.Accounts.GetByID(transfer.ToAccount.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("to_account", "does not exist")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if from.Currency != to.Currency {
+		v.AddError("to_account", "must have the same currency as from_account")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	transfer.FromAccount = from
+	transfer.ToAccount = to
+
+	err = app.models.Transfers.Insert(transfer)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	from.Balance -= transfer.Amount
+	to.Balance += transfer.Amount
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/transfers/%d", transfer.ID))
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"transfer": transfer.ToDTO()}, headers)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) showTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	transfer, err := app.models.Transfers.GetByID(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"transfer": transfer.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) deleteTransferHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := app.readIDParam(r)
+	if err != nil || id < 1 {
+		app.notFoundResponse(w, r)
+		return
+	}
+
+	user := app.contextGetUser(r)
+	err = app.models.Transfers.Delete(id, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			app.notFoundResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "transfer successfully deleted"}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
@@ -5,6 +5,15 @@ import (
 	"meus_gastos/internal/data"
 	"meus_gastos/internal/validator"
 	"net/http"
+	"time"
+)
+
+const (
+	activationCodTTL      = 24 * time.Hour
+	activationCodCooldown = time.Minute
+	passwordResetTTL      = 15 * time.Minute
+	passwordResetCooldown = time.Minute
+	maxCodAttempts        = 5
 )
 
 func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -26,7 +35,7 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	user, err := app.models.Users.GetByCodAndEmail(input.Cod, input.Email)
+	user, err := app.models.Users.GetByEmail(input.Email)
 
 	if err != nil {
 		switch {
@@ -39,16 +48,47 @@ func (app *application) activateUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if user.Activated {
+		v.AddError("code", "user already activated")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if user.CodAttempts >= maxCodAttempts {
+		v.AddError("code", "too many attempts, request a new activation code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !user.CodValid(input.Cod, data.ScopeActivation) {
+		err = app.models.Users.IncrementCodAttempts(user)
+		if err != nil {
+			app.serverErrorResponse(w, r, err)
+			return
+		}
+
+		v.AddError("code", "invalid or expired validation code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
 	user.Activated = true
-	user.Cod = 0
+	user.ClearCod()
 
 	err = app.models.Users.Update(user)
 	if err != nil {
-		app.serverErrorResponse(w, r, err)
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, envelope{"user": user}, nil)
+	app.seedDefaultCategories(user)
+
+	err = app.writeJSON(w, http.StatusOK, envelope{"user": user.ToDTO()}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
@@ -68,8 +108,10 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	codActivation := app.generateRandomCod()
-	user.Cod = codActivation
+	codExpiry := time.Now().Add(activationCodTTL)
+	user.Cod = app.generateRandomCod()
+	user.CodScope = data.ScopeActivation
+	user.CodExpiry = &codExpiry
 
 	v := validator.New()
 
@@ -78,7 +120,7 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.models.Users.Insert(user)
+	err = app.models.Users.Insert(user, &data.Ledger{Name: data.DefaultLedgerName}, "reports:read")
 	if err != nil {
 		switch {
 		case errors.Is(err, data.ErrDuplicateEmail):
@@ -91,22 +133,253 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	/*
-		MANDA COD VERIFICAÇÃO EMAIL
-			app.background(func() {
-				data := map[string]interface{}{
-					"activationToken": codActivation,
-					"userID":          user.ID,
-				}
+	app.sendActivationCod(user, "user_welcome.tmpl")
+
+	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user.ToDTO()}, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) resendActivationCodHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	env := envelope{"message": "if an inactive account with this email exists, a new activation code will be sent to it"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			err = app.writeJSON(w, http.StatusAccepted, env, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	recentlySent := user.CodSentAt != nil && time.Since(*user.CodSentAt) < activationCodCooldown
+
+	if !user.Activated && !recentlySent {
+		expiry := time.Now().Add(activationCodTTL)
+		user.Cod = app.generateRandomCod()
+		user.CodScope = data.ScopeActivation
+		user.CodExpiry = &expiry
+
+		err = app.models.Users.UpdateCodByEmail(user)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.sendActivationCod(user, "user_activation.tmpl")
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) sendActivationCod(user *data.User, templateFile string) {
+	cod := user.Cod
+	email := user.Email
+	name := user.Name
+
+	app.background(func() {
+		data := map[string]any{
+			"name":        name,
+			"email":       email,
+			"code":        cod,
+			"expiryHours": int(activationCodTTL.Hours()),
+		}
+
+		err := app.mailer.Send(email, templateFile, data)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	})
+}
+
+func (app *application) requestPasswordResetHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if data.ValidateEmail(v, input.Email); !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	env := envelope{"message": "if an account with this email exists, a password reset code will be sent to it"}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			err = app.writeJSON(w, http.StatusAccepted, env, nil)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+			}
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	recentlySent := user.CodScope == data.ScopePasswordReset && user.CodSentAt != nil && time.Since(*user.CodSentAt) < passwordResetCooldown
+
+	if user.Activated && !recentlySent {
+		expiry := time.Now().Add(passwordResetTTL)
+		user.Cod = app.generateRandomCod()
+		user.CodScope = data.ScopePasswordReset
+		user.CodExpiry = &expiry
+
+		err = app.models.Users.UpdateCodByEmail(user)
+		if err != nil {
+			switch {
+			case errors.Is(err, data.ErrEditConflict):
+				app.editConflictResponse(w, r)
+			default:
+				app.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.background(func() {
+			data := map[string]any{
+				"email":         user.Email,
+				"code":          user.Cod,
+				"expiryMinutes": int(passwordResetTTL.Minutes()),
+			}
+
+			err := app.mailer.Send(user.Email, "password_reset.tmpl", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusAccepted, env, nil)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *application) resetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email    string `json:"email"`
+		Cod      int    `json:"cod"`
+		Password string `json:"password"`
+	}
+
+	err := app.readJSON(w, r, &input)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	data.ValidateEmail(v, input.Email)
+	data.ValidatePasswordPlaintext(v, input.Password)
+	v.Check(input.Cod != 0, "cod", "must be provided")
+
+	if !v.Valid() {
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.models.Users.GetByEmail(input.Email)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrRecordNotFound):
+			v.AddError("cod", "invalid or expired password reset code")
+			app.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if user.CodScope == data.ScopePasswordReset && user.CodAttempts >= maxCodAttempts {
+		v.AddError("cod", "too many attempts, request a new password reset code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	if !user.CodValid(input.Cod, data.ScopePasswordReset) {
+		if user.CodScope == data.ScopePasswordReset {
+			err = app.models.Users.IncrementCodAttempts(user)
+			if err != nil {
+				app.serverErrorResponse(w, r, err)
+				return
+			}
+		}
 
-				err = app.mailer.Send(user.Email, "user_welcome.tmpl", data)
-				if err != nil {
-					app.logger.PrintError(err, nil)
-				}
-			})
-	*/
+		v.AddError("cod", "invalid or expired password reset code")
+		app.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = user.Password.Set(input.Password)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	user.ClearCod()
+	user.PasswordChangedAt = &now
+
+	err = app.models.Users.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, data.ErrEditConflict):
+			app.editConflictResponse(w, r)
+		default:
+			app.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.models.Tokens.RevokeAllForUser(user.ID)
+	if err != nil {
+		app.serverErrorResponse(w, r, err)
+		return
+	}
 
-	err = app.writeJSON(w, http.StatusCreated, envelope{"user": user}, nil)
+	err = app.writeJSON(w, http.StatusOK, envelope{"message": "your password was successfully reset"}, nil)
 	if err != nil {
 		app.serverErrorResponse(w, r, err)
 	}
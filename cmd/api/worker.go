@@ -0,0 +1,77 @@
+package main
+
+import (
+	"meus_gastos/internal/data"
+	"strconv"
+	"time"
+)
+
+func (app *application) startRecurringWorker() {
+	app.background(func() {
+		ticker := time.NewTicker(app.config.recurring.interval)
+		defer ticker.Stop()
+
+		for {
+			app.materializeRecurringTransactions()
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func (app *application) startTokenCleanup() {
+	app.background(func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for {
+			err := app.models.Tokens.DeleteExpired()
+			if err != nil {
+				app.logger.PrintError(err, map[string]string{
+					"worker": "token_cleanup",
+				})
+			}
+
+			select {
+			case <-app.shutdown:
+				return
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+func (app *application) materializeRecurringTransactions() {
+	today := data.Today()
+
+	ids, err := app.models.Recurring.DueIDs(today)
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"worker": "recurring_transactions",
+		})
+		return
+	}
+
+	created := 0
+	for _, id := range ids {
+		n, err := app.models.Recurring.Materialize(id, today)
+		if err != nil {
+			app.logger.PrintError(err, map[string]string{
+				"worker":                   "recurring_transactions",
+				"recurring_transaction_id": strconv.FormatInt(id, 10),
+			})
+			continue
+		}
+		created += n
+	}
+
+	if created > 0 {
+		app.logger.PrintInfo("recurring transactions materialized", map[string]string{
+			"created": strconv.Itoa(created),
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+type AccountType int
+
+const (
+	CHECKING AccountType = iota + 1
+	SAVINGS
+	CREDIT_CARD
+	CASH
+)
+
+func (t AccountType) String() string {
+	switch t {
+	case CHECKING:
+		return "CHECKING"
+	case SAVINGS:
+		return "SAVINGS"
+	case CREDIT_CARD:
+		return "CREDIT_CARD"
+	case CASH:
+		return "CASH"
+	default:
+		return "Unknown"
+	}
+}
+
+func AccountTypeFromString(s string) AccountType {
+	switch s {
+	case "CHECKING":
+		return CHECKING
+	case "SAVINGS":
+		return SAVINGS
+	case "CREDIT_CARD":
+		return CREDIT_CARD
+	case "CASH":
+		return CASH
+	default:
+		return 0
+	}
+}
+
+var (
+	ErrDuplicateAccount = errors.New("duplicate account")
+)
+
+const accountBalance = `
+	a.initial_balance + COALESCE((
+		SELECT SUM(CASE
+			WHEN t.transfer_id IS NOT NULL THEN t.amount
+			WHEN c.type = 1 THEN t.amount
+			ELSE -t.amount
+		END)
+		FROM transactions t
+		LEFT JOIN categories c ON c.id = t.category_id
+		WHERE t.account_id = a.id AND t.deleted = false
+	), 0)`
+
+type Account struct {
+	ID             int64
+	CreatedAt      time.Time
+	User           *User
+	Name           string
+	Type           AccountType
+	Currency       string
+	InitialBalance Money
+	Balance        Money
+	ClosingDay     *int
+	DueDay         *int
+	Deleted        bool
+	Version        int
+}
+
+type AccountDTO struct {
+	ID             *int64     `json:"account_id"`
+	Version        *int       `json:"version"`
+	User           *UserDTO   `json:"user,omitempty"`
+	Name           *string    `json:"name"`
+	Type           *string    `json:"type"`
+	Currency       *string    `json:"currency"`
+	InitialBalance *Money     `json:"initial_balance"`
+	Balance        *Money     `json:"balance"`
+	ClosingDay     *int       `json:"closing_day,omitempty"`
+	DueDay         *int       `json:"due_day,omitempty"`
+	CreatedAt      *time.Time `json:"created_at"`
+}
+
+type AccountEntry struct {
+	TransactionID int64  `json:"transaction_id"`
+	OccurredOn    Date   `json:"occurred_on"`
+	Description   string `json:"description"`
+	CategoryID    *int64 `json:"category_id,omitempty"`
+	TransferID    *int64 `json:"transfer_id,omitempty"`
+	Amount        Money  `json:"amount"`
+	Balance       Money  `json:"balance"`
+}
+
+type AccountModel struct {
+	DB *sql.DB
+}
+
+func (a *Account) ToDTO() *AccountDTO {
+	dto := &AccountDTO{}
+
+	if a.ID != 0 {
+		dto.ID = &a.ID
+	}
+
+	if a.Version != 0 {
+		dto.Version = &a.Version
+	}
+
+	if a.User != nil {
+		dto.User = a.User.ToDTO()
+	}
+
+	if a.Name != "" {
+		dto.Name = &a.Name
+	}
+
+	if a.Type != 0 {
+		typeStr := a.Type.String()
+		dto.Type = &typeStr
+	}
+
+	if a.Currency != "" {
+		dto.Currency = &a.Currency
+	}
+
+	dto.ClosingDay = a.ClosingDay
+	dto.DueDay = a.DueDay
+
+	if !a.CreatedAt.IsZero() {
+		dto.InitialBalance = &a.InitialBalance
+		dto.Balance = &a.Balance
+		dto.CreatedAt = &a.CreatedAt
+	}
+
+	return dto
+}
+
+func (dto *AccountDTO) ToModel() *Account {
+	account := &Account{}
+
+	if dto.ID != nil {
+		account.ID = *dto.ID
+	}
+	if dto.Version != nil {
+		account.Version = *dto.Version
+	}
+	if dto.User != nil {
+		account.User = dto.User.ToModel()
+	}
+	if dto.Name != nil {
+		account.Name = *dto.Name
+	}
+	if dto.Type != nil {
+		account.Type = AccountTypeFromString(*dto.Type)
+	}
+	if dto.Currency != nil {
+		account.Currency = *dto.Currency
+	}
+	if dto.InitialBalance != nil {
+		account.InitialBalance = *dto.InitialBalance
+	}
+	account.ClosingDay = dto.ClosingDay
+	account.DueDay = dto.DueDay
+
+	return account
+}
+
+func (dto *AccountDTO) ToDTOUpdateAccount(account *Account) {
+	if dto.Version != nil {
+		account.Version = *dto.Version
+	}
+
+	if dto.Name != nil {
+		account.Name = *dto.Name
+	}
+
+	if dto.Type != nil {
+		account.Type = AccountTypeFromString(*dto.Type)
+		if account.Type != CREDIT_CARD {
+			account.ClosingDay = nil
+			account.DueDay = nil
+		}
+	}
+
+	if dto.InitialBalance != nil {
+		account.InitialBalance = *dto.InitialBalance
+	}
+
+	if dto.ClosingDay != nil {
+		account.ClosingDay = dto.ClosingDay
+	}
+
+	if dto.DueDay != nil {
+		account.DueDay = dto.DueDay
+	}
+}
+
+func (m AccountModel) Insert(account *Account) error {
+	query := `
+	INSERT INTO accounts (user_id, name, type, currency, initial_balance, closing_day, due_day)
+	VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), (SELECT currency FROM users WHERE id = $1)), $5, $6, $7)
+	RETURNING id, created_at, version, currency
+	`
+
+	args := []any{
+		account.User.ID,
+		account.Name,
+		account.Type,
+		account.Currency,
+		account.InitialBalance,
+		account.ClosingDay,
+		account.DueDay,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&account.ID,
+		&account.CreatedAt,
+		&account.Version,
+		&account.Currency,
+	)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_accounts_user_name"`:
+			return ErrDuplicateAccount
+		default:
+			return err
+		}
+	}
+
+	account.Balance = account.InitialBalance
+
+	return nil
+}
+
+func (m AccountModel) GetByID(id int64, userID int64) (*Account, error) {
+	query := `
+	SELECT a.id, a.created_at, a.user_id, a.name, a.type, a.currency, a.initial_balance, ` + accountBalance + `, a.closing_day, a.due_day, a.deleted, a.version
+	FROM accounts a
+	WHERE a.id = $1 AND a.user_id = $2 AND a.deleted = false
+	`
+
+	account := Account{
+		User: &User{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&account.ID,
+		&account.CreatedAt,
+		&account.User.ID,
+		&account.Name,
+		&account.Type,
+		&account.Currency,
+		&account.InitialBalance,
+		&account.Balance,
+		&account.ClosingDay,
+		&account.DueDay,
+		&account.Deleted,
+		&account.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &account, nil
+}
+
+func (m AccountModel) GetAll(userID int64, accountType AccountType, filters Filters) ([]*Account, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), a.id, a.created_at, a.user_id, a.name, a.type, a.currency, a.initial_balance, `+accountBalance+`, a.closing_day, a.due_day, a.deleted, a.version
+	FROM accounts a
+	WHERE a.user_id = $1 AND a.deleted = false
+	AND ($2 = 0 OR a.type = $2)
+	ORDER BY a.%s %s, a.id ASC
+	LIMIT $3 OFFSET $4
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, accountType, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	accounts := []*Account{}
+
+	for rows.Next() {
+		account := Account{
+			User: &User{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&account.ID,
+			&account.CreatedAt,
+			&account.User.ID,
+			&account.Name,
+			&account.Type,
+			&account.Currency,
+			&account.InitialBalance,
+			&account.Balance,
+			&account.ClosingDay,
+			&account.DueDay,
+			&account.Deleted,
+			&account.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		accounts = append(accounts, &account)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return accounts, metaData, nil
+}
+
+func (m AccountModel) GetEntries(id int64, userID int64, startDate, endDate *time.Time, filters Filters) ([]*AccountEntry, Metadata, error) {
+	query := `
+	SELECT count(*) OVER(), e.id, e.occurred_on, e.description, e.category_id, e.transfer_id, e.amount, e.balance
+	FROM (
+		SELECT t.id, t.occurred_on, t.description, t.category_id, t.transfer_id, s.amount,
+			a.initial_balance + SUM(s.amount) OVER (ORDER BY t.occurred_on ASC, t.id ASC) AS balance
+		FROM transactions t
+		INNER JOIN accounts a ON a.id = t.account_id
+		LEFT JOIN categories c ON c.id = t.category_id
+		CROSS JOIN LATERAL (
+			SELECT CASE
+				WHEN t.transfer_id IS NOT NULL THEN t.amount
+				WHEN c.type = 1 THEN t.amount
+				ELSE -t.amount
+			END AS amount
+		) s
+		WHERE t.account_id = $1 AND a.user_id = $2 AND a.deleted = false AND t.deleted = false
+	) e
+	WHERE ($3::date IS NULL OR e.occurred_on >= $3::date)
+	AND ($4::date IS NULL OR e.occurred_on <= $4::date)
+	ORDER BY e.occurred_on DESC, e.id DESC
+	LIMIT $5 OFFSET $6
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{
+		id,
+		userID,
+		nullDate(startDate),
+		nullDate(endDate),
+		filters.limit(),
+		filters.offset(),
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*AccountEntry{}
+
+	for rows.Next() {
+		var entry AccountEntry
+
+		err := rows.Scan(
+			&totalRecords,
+			&entry.TransactionID,
+			&entry.OccurredOn,
+			&entry.Description,
+			&entry.CategoryID,
+			&entry.TransferID,
+			&entry.Amount,
+			&entry.Balance,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return entries, metaData, nil
+}
+
+func (m AccountModel) Update(account *Account, userID int64) error {
+	query := `
+	UPDATE accounts
+	SET name = $1,
+		type = $2,
+		initial_balance = $3,
+		closing_day = $4,
+		due_day = $5,
+		version = version + 1
+	WHERE
+		id = $6
+		AND user_id = $7
+		AND deleted = false
+		AND version = $8
+	RETURNING version
+	`
+
+	args := []any{
+		account.Name,
+		account.Type,
+		account.InitialBalance,
+		account.ClosingDay,
+		account.DueDay,
+		account.ID,
+		userID,
+		account.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&account.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_accounts_user_name"`:
+			return ErrDuplicateAccount
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m AccountModel) Delete(id int64, userID int64) error {
+	query := `
+	UPDATE accounts
+	SET
+		deleted = true
+	WHERE
+		id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateAccount(v *validator.Validator, account *Account) {
+	v.Check(account.User != nil, "user", "must be provided")
+	v.Check(account.Name != "", "name", "must be provided")
+	v.Check(len(account.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(account.Type != 0, "type", "must be CHECKING, SAVINGS, CREDIT_CARD or CASH")
+	v.Check(account.InitialBalance.Abs() <= MaxMoney, "initial_balance", "must not be more than 9999999999999.99")
+
+	if account.Currency != "" {
+		ValidateCurrency(v, "currency", account.Currency)
+	}
+
+	if account.Type == CREDIT_CARD {
+		v.Check(account.ClosingDay != nil, "closing_day", "must be provided")
+		v.Check(account.DueDay != nil, "due_day", "must be provided")
+
+		if account.ClosingDay != nil {
+			v.Check(*account.ClosingDay >= 1 && *account.ClosingDay <= 31, "closing_day", "must be between 1 and 31")
+		}
+
+		if account.DueDay != nil {
+			v.Check(*account.DueDay >= 1 && *account.DueDay <= 31, "due_day", "must be between 1 and 31")
+		}
+	} else {
+		v.Check(account.ClosingDay == nil, "closing_day", "must only be provided for CREDIT_CARD accounts")
+		v.Check(account.DueDay == nil, "due_day", "must only be provided for CREDIT_CARD accounts")
+	}
+}
+
+func nullAccountID(account *Account) any {
+	if account == nil || account.ID == 0 {
+		return nil
+	}
+	return account.ID
+}
+
+func accountFromNullID(id sql.NullInt64) *Account {
+	if !id.Valid {
+		return nil
+	}
+	return &Account{ID: id.Int64}
+}
@@ -0,0 +1,420 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+type Budget struct {
+	ID        int64
+	CreatedAt time.Time
+	User      *User
+	Category  *Category
+	Amount    Money
+	Deleted   bool
+	Version   int
+}
+
+type BudgetDTO struct {
+	ID        *int64       `json:"budget_id"`
+	Version   *int         `json:"version"`
+	User      *UserDTO     `json:"user"`
+	Category  *CategoryDTO `json:"category"`
+	Amount    *Money       `json:"amount"`
+	CreatedAt *time.Time   `json:"created_at"`
+}
+
+type BudgetStatus struct {
+	BudgetID     int64                `json:"budget_id"`
+	CategoryID   int64                `json:"category_id"`
+	CategoryName string               `json:"category_name"`
+	Month        string               `json:"month"`
+	Amount       Money                `json:"amount"`
+	Spent        Money                `json:"spent"`
+	Remaining    Money                `json:"remaining"`
+	PercentUsed  float64              `json:"percent_used"`
+	Overspent    bool                 `json:"overspent"`
+	Unconverted  []*UnconvertedAmount `json:"unconverted"`
+}
+
+type UnconvertedAmount struct {
+	Currency string `json:"currency"`
+	Total    Money  `json:"total"`
+	Count    int    `json:"count"`
+}
+
+type BudgetModel struct {
+	DB *sql.DB
+}
+
+var (
+	ErrDuplicateBudget = errors.New("duplicate budget")
+)
+
+func (b *Budget) ToDTO() *BudgetDTO {
+	dto := &BudgetDTO{}
+
+	if b.ID != 0 {
+		dto.ID = &b.ID
+	}
+
+	if b.Version != 0 {
+		dto.Version = &b.Version
+	}
+
+	if b.User != nil {
+		dto.User = b.User.ToDTO()
+	}
+
+	if b.Category != nil {
+		dto.Category = b.Category.ToDTO()
+	}
+
+	if b.Amount != 0 {
+		dto.Amount = &b.Amount
+	}
+
+	dto.CreatedAt = &b.CreatedAt
+
+	return dto
+}
+
+func (dto *BudgetDTO) ToModel() *Budget {
+	budget := &Budget{}
+
+	if dto.ID != nil {
+		budget.ID = *dto.ID
+	}
+	if dto.Version != nil {
+		budget.Version = *dto.Version
+	}
+	if dto.User != nil {
+		budget.User = dto.User.ToModel()
+	}
+	if dto.Category != nil {
+		budget.Category = dto.Category.ToModel()
+	}
+	if dto.Amount != nil {
+		budget.Amount = *dto.Amount
+	}
+
+	return budget
+}
+
+func (dto *BudgetDTO) ToDTOUpdateBudget(budget *Budget) {
+	if dto.Version != nil {
+		budget.Version = *dto.Version
+	}
+
+	if dto.Category != nil {
+		budget.Category = dto.Category.ToModel()
+	}
+
+	if dto.Amount != nil {
+		budget.Amount = *dto.Amount
+	}
+}
+
+func (m BudgetModel) Insert(budget *Budget) error {
+	query := `
+	INSERT INTO budgets (user_id, category_id, amount)
+	VALUES ($1, $2, $3)
+	RETURNING id, created_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, budget.User.ID, budget.Category.ID, budget.Amount).Scan(
+		&budget.ID,
+		&budget.CreatedAt,
+		&budget.Version,
+	)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_budgets_user_category"`:
+			return ErrDuplicateBudget
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m BudgetModel) GetByID(id int64, userID int64) (*Budget, error) {
+	query := `
+	SELECT id, created_at, user_id, category_id, amount, deleted, version
+	FROM budgets
+	WHERE id = $1 AND user_id = $2 AND deleted = false
+	`
+
+	budget := Budget{
+		User:     &User{},
+		Category: &Category{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&budget.ID,
+		&budget.CreatedAt,
+		&budget.User.ID,
+		&budget.Category.ID,
+		&budget.Amount,
+		&budget.Deleted,
+		&budget.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &budget, nil
+}
+
+func (m BudgetModel) GetAll(userID int64, filters Filters) ([]*Budget, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, user_id, category_id, amount, deleted, version
+	FROM budgets
+	WHERE user_id = $1 AND deleted = false
+	ORDER BY %s %s, id ASC
+	LIMIT $2 OFFSET $3
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	budgets := []*Budget{}
+
+	for rows.Next() {
+		budget := Budget{
+			User:     &User{},
+			Category: &Category{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&budget.ID,
+			&budget.CreatedAt,
+			&budget.User.ID,
+			&budget.Category.ID,
+			&budget.Amount,
+			&budget.Deleted,
+			&budget.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		budgets = append(budgets, &budget)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return budgets, metaData, nil
+}
+
+func (m BudgetModel) Update(budget *Budget, userID int64) error {
+	query := `
+	UPDATE budgets
+	SET category_id = $1,
+		amount = $2,
+		version = version + 1
+	WHERE
+		id = $3
+		AND user_id = $4
+		AND deleted = false
+		AND version = $5
+	RETURNING version
+	`
+
+	args := []any{
+		budget.Category.ID,
+		budget.Amount,
+		budget.ID,
+		userID,
+		budget.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&budget.Version)
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "idx_budgets_user_category"`:
+			return ErrDuplicateBudget
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m BudgetModel) Delete(id int64, userID int64) error {
+	query := `
+	UPDATE budgets
+	SET
+		deleted = true
+	WHERE
+		id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m BudgetModel) GetStatus(userID int64, month time.Time) ([]*BudgetStatus, error) {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	query := `
+	WITH RECURSIVE ` + convertedTransactions + `, ` + categoryTree + `
+	SELECT b.id, c.id, c.name, b.amount, COALESCE((
+		SELECT SUM(t.amount)
+		FROM converted t
+		INNER JOIN category_tree ct ON ct.id = t.category_id
+		WHERE ct.ancestor_id = b.category_id
+		AND t.occurred_on >= $2
+		AND t.occurred_on < $3
+	), 0)
+	FROM budgets b
+	INNER JOIN categories c ON c.id = b.category_id
+	WHERE b.user_id = $1 AND b.deleted = false AND c.deleted = false
+	ORDER BY c.name ASC, b.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, NewDate(start), NewDate(end))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	statuses := []*BudgetStatus{}
+	byID := map[int64]*BudgetStatus{}
+
+	for rows.Next() {
+		status := BudgetStatus{
+			Month:       start.Format("2006-01"),
+			Unconverted: []*UnconvertedAmount{},
+		}
+
+		err := rows.Scan(
+			&status.BudgetID,
+			&status.CategoryID,
+			&status.CategoryName,
+			&status.Amount,
+			&status.Spent,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		status.Remaining = status.Amount - status.Spent
+		status.PercentUsed = status.Spent.Percent(status.Amount)
+		status.Overspent = status.Spent > status.Amount
+
+		statuses = append(statuses, &status)
+		byID[status.BudgetID] = &status
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	unconverted := `
+	WITH RECURSIVE ` + convertedTransactions + `, ` + categoryTree + `
+	SELECT b.id, t.currency, SUM(t.original_amount), COUNT(t.id)
+	FROM budgets b
+	INNER JOIN category_tree ct ON ct.ancestor_id = b.category_id
+	INNER JOIN converted t ON t.category_id = ct.id
+	WHERE b.user_id = $1 AND b.deleted = false
+	AND t.amount IS NULL
+	AND t.occurred_on >= $2
+	AND t.occurred_on < $3
+	GROUP BY b.id, t.currency
+	ORDER BY b.id ASC, t.currency ASC
+	`
+
+	rows, err = m.DB.QueryContext(ctx, unconverted, userID, NewDate(start), NewDate(end))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var budgetID int64
+		amount := UnconvertedAmount{}
+
+		err := rows.Scan(&budgetID, &amount.Currency, &amount.Total, &amount.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		if status, ok := byID[budgetID]; ok {
+			status.Unconverted = append(status.Unconverted, &amount)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+func ValidateBudget(v *validator.Validator, budget *Budget) {
+	v.Check(budget.User != nil, "user", "must be provided")
+	v.Check(budget.Category != nil, "category", "must be provided")
+	v.Check(budget.Amount > 0, "amount", "must be positive")
+	v.Check(budget.Amount <= MaxMoney, "amount", "must not be more than 9999999999999.99")
+}
@@ -0,0 +1,146 @@
+package data
+
+import (
+	"context"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+const (
+	InvoiceClosed   = "CLOSED"
+	InvoiceOpen     = "OPEN"
+	InvoiceUpcoming = "UPCOMING"
+)
+
+type Invoice struct {
+	Month        string `json:"month"`
+	ClosingDate  Date   `json:"closing_date"`
+	DueDate      Date   `json:"due_date"`
+	Status       string `json:"status"`
+	Total        Money  `json:"total"`
+	Transactions int    `json:"transactions"`
+}
+
+func clampedDate(year int, month time.Month, day int) Date {
+	firstOfMonth := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return NewDate(firstOfMonth.AddDate(0, 0, day-1))
+}
+
+func (a *Account) HasBillingCycle() bool {
+	return a.ClosingDay != nil && a.DueDay != nil
+}
+
+func ValidateBillingCycle(v *validator.Validator, account *Account) {
+	v.Check(account.ClosingDay != nil, "closing_day", "must be set on the account")
+	v.Check(account.DueDay != nil, "due_day", "must be set on the account")
+}
+
+func (a *Account) closingDate(year int, month time.Month) Date {
+	return clampedDate(year, month, *a.ClosingDay)
+}
+
+func (a *Account) InvoiceClosing(d Date) Date {
+	closing := a.closingDate(d.Year(), d.Month())
+	if d.After(closing.Time) {
+		closing = a.closingDate(d.Year(), d.Month()+1)
+	}
+	return closing
+}
+
+func (a *Account) ShiftInvoice(closing Date, months int) Date {
+	return a.closingDate(closing.Year(), closing.Month()+time.Month(months))
+}
+
+func (a *Account) InvoiceDue(closing Date) Date {
+	if *a.DueDay > *a.ClosingDay {
+		return clampedDate(closing.Year(), closing.Month(), *a.DueDay)
+	}
+	return clampedDate(closing.Year(), closing.Month()+1, *a.DueDay)
+}
+
+func (m AccountModel) GetInvoices(account *Account, today Date) ([]*Invoice, error) {
+	query := `
+	SELECT t.occurred_on, CASE WHEN c.type = 1 THEN -t.amount ELSE t.amount END
+	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE t.account_id = $1 AND t.user_id = $2 AND t.deleted = false
+	AND t.occurred_on > $3
+	ORDER BY t.occurred_on ASC, t.id ASC
+	`
+
+	open := account.InvoiceClosing(today)
+	previous := account.ShiftInvoice(open, -1)
+	since := account.ShiftInvoice(open, -2)
+
+	invoices := []*Invoice{}
+	byClosing := map[Date]*Invoice{}
+
+	invoiceFor := func(closing Date) *Invoice {
+		invoice, ok := byClosing[closing]
+		if !ok {
+			invoice = &Invoice{
+				Month:       closing.Format("2006-01"),
+				ClosingDate: closing,
+				DueDate:     account.InvoiceDue(closing),
+				Status:      InvoiceUpcoming,
+			}
+
+			switch {
+			case closing.Before(open.Time):
+				invoice.Status = InvoiceClosed
+			case closing.Equal(open.Time):
+				invoice.Status = InvoiceOpen
+			}
+
+			byClosing[closing] = invoice
+			invoices = append(invoices, invoice)
+		}
+		return invoice
+	}
+
+	if !today.After(account.InvoiceDue(previous).Time) {
+		invoiceFor(previous)
+	}
+	invoiceFor(open)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, account.ID, account.User.ID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var occurredOn Date
+		var amount Money
+
+		err := rows.Scan(&occurredOn, &amount)
+		if err != nil {
+			return nil, err
+		}
+
+		closing := account.InvoiceClosing(occurredOn)
+		if closing.Equal(previous.Time) && byClosing[previous] == nil {
+			continue
+		}
+
+		invoice := invoiceFor(closing)
+		invoice.Total += amount
+		invoice.Transactions++
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invoices, nil
+}
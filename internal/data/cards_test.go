@@ -0,0 +1,91 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) Date {
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return NewDate(t)
+}
+
+func creditCard(closingDay, dueDay int) *Account {
+	return &Account{
+		Type:       CREDIT_CARD,
+		Currency:   "BRL",
+		ClosingDay: &closingDay,
+		DueDay:     &dueDay,
+	}
+}
+
+func TestInvoiceClosing(t *testing.T) {
+	tests := []struct {
+		name       string
+		closingDay int
+		date       string
+		want       string
+	}{
+		{"before closing", 25, "2024-01-10", "2024-01-25"},
+		{"on closing", 25, "2024-01-25", "2024-01-25"},
+		{"after closing", 25, "2024-01-26", "2024-02-25"},
+		{"year rollover", 25, "2024-12-26", "2025-01-25"},
+		{"clamped to leap february", 31, "2024-02-10", "2024-02-29"},
+		{"clamped to february", 31, "2023-02-28", "2023-02-28"},
+		{"clamped to thirty day month", 31, "2024-04-15", "2024-04-30"},
+		{"after closing into short month", 30, "2024-01-31", "2024-02-29"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := creditCard(tt.closingDay, 10).InvoiceClosing(date(tt.date))
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoiceDue(t *testing.T) {
+	tests := []struct {
+		name       string
+		closingDay int
+		dueDay     int
+		closing    string
+		want       string
+	}{
+		{"due after closing", 5, 15, "2024-02-05", "2024-02-15"},
+		{"due before closing", 25, 5, "2024-12-25", "2025-01-05"},
+		{"due on closing day", 10, 10, "2024-04-10", "2024-05-10"},
+		{"due clamped to month end", 20, 31, "2024-04-20", "2024-04-30"},
+		{"clamped closing", 31, 10, "2024-02-29", "2024-03-10"},
+		{"due clamped to february", 31, 30, "2024-01-31", "2024-02-29"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := creditCard(tt.closingDay, tt.dueDay).InvoiceDue(date(tt.closing))
+			if got.String() != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasBillingCycle(t *testing.T) {
+	if !creditCard(25, 5).HasBillingCycle() {
+		t.Error("got false for an account with closing and due days")
+	}
+
+	closingDay := 25
+	if (&Account{Type: CREDIT_CARD, ClosingDay: &closingDay}).HasBillingCycle() {
+		t.Error("got true for an account without a due day")
+	}
+
+	if (&Account{Type: CREDIT_CARD}).HasBillingCycle() {
+		t.Error("got true for an account without closing and due days")
+	}
+}
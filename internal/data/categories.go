@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"meus_gastos/internal/validator"
+	"slices"
 	"time"
 )
 
@@ -16,10 +17,16 @@ type Category struct {
 	Type      TypeCategoria
 	Color     string
 	User      *User
+	Ledger    *Ledger
+	Parent    *Category
+	Ancestors []int64
+	Height    int
 	Deleted   bool
 	Version   int
 }
 
+const MaxCategoryDepth = 5
+
 type TypeCategoria int
 
 const (
@@ -56,9 +63,16 @@ type CategoryDTO struct {
 	Type      *string    `json:"type"`
 	Color     *string    `json:"color"`
 	User      *UserDTO   `json:"user"`
+	Ledger    *LedgerDTO `json:"ledger,omitempty"`
+	ParentID  *int64     `json:"parent_id"`
 	Version   *int       `json:"version"`
 }
 
+type CategoryNode struct {
+	*CategoryDTO
+	Children []*CategoryNode `json:"children"`
+}
+
 type CategoryModel struct {
 	DB *sql.DB
 }
@@ -89,6 +103,14 @@ func (c *Category) ToDTO() *CategoryDTO {
 	if c.User != nil {
 		user = c.User.ToDTO()
 	}
+	var ledger *LedgerDTO
+	if c.Ledger != nil {
+		ledger = c.Ledger.ToDTO()
+	}
+	var parentID *int64
+	if c.Parent != nil {
+		parentID = &c.Parent.ID
+	}
 	var version *int
 	if c.Version != 0 {
 		version = &c.Version
@@ -101,6 +123,8 @@ func (c *Category) ToDTO() *CategoryDTO {
 		Type:      &typeStr,
 		Color:     color,
 		User:      user,
+		Ledger:    ledger,
+		ParentID:  parentID,
 		Version:   version,
 	}
 }
@@ -141,6 +165,16 @@ func (c *CategoryDTO) ToModel() *Category {
 		user = c.User.ToModel()
 	}
 
+	var ledger *Ledger
+	if c.Ledger != nil {
+		ledger = c.Ledger.ToModel()
+	}
+
+	var parent *Category
+	if c.ParentID != nil && *c.ParentID != 0 {
+		parent = &Category{ID: *c.ParentID}
+	}
+
 	return &Category{
 		ID:        id,
 		CreatedAt: createdAt,
@@ -148,6 +182,8 @@ func (c *CategoryDTO) ToModel() *Category {
 		Type:      tipo,
 		Color:     color,
 		User:      user,
+		Ledger:    ledger,
+		Parent:    parent,
 		Version:   version,
 	}
 }
@@ -173,6 +209,13 @@ func (c *CategoryDTO) ToDTOUpdateCategory(category *Category) *Category {
 		category.User = c.User.ToModel()
 	}
 
+	if c.ParentID != nil {
+		category.Parent = nil
+		if *c.ParentID != 0 {
+			category.Parent = &Category{ID: *c.ParentID}
+		}
+	}
+
 	if c.Version != nil {
 		category.Version = *c.Version
 	}
@@ -185,7 +228,7 @@ var (
 )
 
 func (m CategoryModel) Insert(category *Category) error {
-	exists, err := m.ExistsByName(category.Name, category.User.ID)
+	exists, err := m.ExistsByName(category.Name, category.Ledger.ID)
 	if err != nil {
 		return err
 	}
@@ -195,8 +238,8 @@ func (m CategoryModel) Insert(category *Category) error {
 	}
 
 	query := `
-	INSERT INTO categories (name, type, color, user_id)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO categories (name, type, color, user_id, ledger_id, parent_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
 	RETURNING id, created_at, version
 	`
 
@@ -205,6 +248,8 @@ func (m CategoryModel) Insert(category *Category) error {
 		category.Type,
 		category.Color,
 		category.User.ID,
+		category.Ledger.ID,
+		nullCategoryID(category.Parent),
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -225,15 +270,19 @@ func (m CategoryModel) Insert(category *Category) error {
 
 func (m CategoryModel) GetByID(id int64, userID int64) (*Category, error) {
 	query := `
-	SELECT id, created_at, name, type, color, user_id,version
-	FROM categories
-	WHERE id = $1 AND user_id = $2 AND deleted = false
+	SELECT c.id, c.created_at, c.name, c.type, c.color, c.user_id, c.version, c.ledger_id, lm.role, c.parent_id
+	FROM categories c
+	INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id AND lm.user_id = $2
+	WHERE c.id = $1 AND c.deleted = false
 	`
 
 	category := Category{
-		User: &User{},
+		User:   &User{},
+		Ledger: &Ledger{},
 	}
 
+	var parentID sql.NullInt64
+
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
@@ -245,6 +294,9 @@ func (m CategoryModel) GetByID(id int64, userID int64) (*Category, error) {
 		&category.Color,
 		&category.User.ID,
 		&category.Version,
+		&category.Ledger.ID,
+		&category.Ledger.Role,
+		&parentID,
 	)
 
 	if err != nil {
@@ -256,23 +308,27 @@ func (m CategoryModel) GetByID(id int64, userID int64) (*Category, error) {
 		}
 	}
 
+	category.Parent = categoryFromNullID(parentID)
+
 	return &category, nil
 }
 
-func (m CategoryModel) GetAll(name string, userID int64, filters Filters) ([]*Category, Metadata, error) {
+func (m CategoryModel) GetAll(name string, userID int64, ledgerID int64, filters Filters) ([]*Category, Metadata, error) {
 	query := fmt.Sprintf(`
-	SELECT count(*) OVER(), id, created_at, name, type, color, user_id,version
-	FROM categories
-	WHERE (to_tsvector('simple', name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND user_id = $2 AND deleted = false
-	ORDER BY %s %s, id ASC
-	LIMIT $3 OFFSET $4
+	SELECT count(*) OVER(), c.id, c.created_at, c.name, c.type, c.color, c.user_id, c.version, c.ledger_id, lm.role, c.parent_id
+	FROM categories c
+	INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id AND lm.user_id = $2
+	WHERE (to_tsvector('simple', c.name) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND ($3 = 0 OR c.ledger_id = $3)
+	AND c.deleted = false
+	ORDER BY c.%s %s, c.id ASC
+	LIMIT $4 OFFSET $5
 	`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{name, userID, filters.limit(), filters.offset()}
+	args := []any{name, userID, ledgerID, filters.limit(), filters.offset()}
 
 	rows, err := m.DB.QueryContext(ctx, query, args...)
 
@@ -287,8 +343,10 @@ func (m CategoryModel) GetAll(name string, userID int64, filters Filters) ([]*Ca
 
 	for rows.Next() {
 		category := Category{
-			User: &User{},
+			User:   &User{},
+			Ledger: &Ledger{},
 		}
+		var parentID sql.NullInt64
 
 		err := rows.Scan(
 			&totalRecords,
@@ -299,12 +357,16 @@ func (m CategoryModel) GetAll(name string, userID int64, filters Filters) ([]*Ca
 			&category.Color,
 			&category.User.ID,
 			&category.Version,
+			&category.Ledger.ID,
+			&category.Ledger.Role,
+			&parentID,
 		)
 
 		if err != nil {
 			return nil, Metadata{}, err
 		}
 
+		category.Parent = categoryFromNullID(parentID)
 		categories = append(categories, &category)
 	}
 
@@ -323,12 +385,13 @@ func (m CategoryModel) Update(category *Category, userID int64) error {
 		name = $1, 
 		type = $2, 
 		color = $3, 
+		parent_id = $4,
 		version = version + 1
 	WHERE 
-		id = $4 
-		AND user_id = $5 
+		id = $5 
+		AND ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $6 AND role <> 'viewer')
 		AND deleted = false 
-		AND version = $6
+		AND version = $7
 	RETURNING version
 	`
 
@@ -336,6 +399,7 @@ func (m CategoryModel) Update(category *Category, userID int64) error {
 		category.Name,
 		category.Type,
 		category.Color,
+		nullCategoryID(category.Parent),
 		category.ID,
 		userID,
 		category.Version,
@@ -360,43 +424,223 @@ func (m CategoryModel) Update(category *Category, userID int64) error {
 
 func (m CategoryModel) Delete(id int64, userID int64) error {
 	query := `
-	UPDATE from categories
+	UPDATE categories
 	SET
 		deleted = true
 	WHERE
 		id = $1
-		AND user_id = $2
+		AND ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $2 AND role <> 'viewer')
 		AND deleted = false
+	RETURNING parent_id
+	`
+
+	reparentQuery := `
+	UPDATE categories
+	SET parent_id = $2, version = version + 1
+	WHERE parent_id = $1
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	result, err := m.DB.ExecContext(ctx, query, id, userID)
-
+	tx, err := m.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	rowsAffected, err := result.RowsAffected()
+	var parentID sql.NullInt64
+	err = tx.QueryRowContext(ctx, query, id, userID).Scan(&parentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, reparentQuery, id, parentID)
 	if err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
-		return ErrRecordNotFound
+	return tx.Commit()
+}
+
+func (m CategoryModel) GetAncestorIDs(id int64) ([]int64, error) {
+	query := `
+	WITH RECURSIVE ancestors AS (
+		SELECT id, parent_id, 0 AS depth
+		FROM categories
+		WHERE id = $1
+		UNION ALL
+		SELECT c.id, c.parent_id, a.depth + 1
+		FROM categories c
+		INNER JOIN ancestors a ON c.id = a.parent_id
+		WHERE a.depth < $2
+	)
+	SELECT id FROM ancestors
+	WHERE id <> $1
+	ORDER BY depth DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, id, MaxCategoryDepth*2)
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	defer rows.Close()
+
+	ancestors := []int64{}
+
+	for rows.Next() {
+		var ancestorID int64
+
+		err := rows.Scan(&ancestorID)
+		if err != nil {
+			return nil, err
+		}
+
+		ancestors = append(ancestors, ancestorID)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ancestors, nil
 }
 
-func (m CategoryModel) ExistsByName(name string, userID int64) (bool, error) {
+func (m CategoryModel) GetSubtreeHeight(id int64) (int, error) {
+	query := `
+	WITH RECURSIVE descendants AS (
+		SELECT id, 0 AS depth
+		FROM categories
+		WHERE id = $1
+		UNION ALL
+		SELECT c.id, d.depth + 1
+		FROM categories c
+		INNER JOIN descendants d ON c.parent_id = d.id
+		WHERE c.deleted = false AND d.depth < $2
+	)
+	SELECT MAX(depth) FROM descendants
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var height int
+	err := m.DB.QueryRowContext(ctx, query, id, MaxCategoryDepth*2).Scan(&height)
+	if err != nil {
+		return 0, err
+	}
+
+	return height, nil
+}
+
+func (m CategoryModel) GetTree(userID int64, ledgerID int64) ([]*CategoryNode, error) {
+	query := `
+	SELECT c.id, c.created_at, c.name, c.type, c.color, c.user_id, c.version, c.ledger_id, lm.role, c.parent_id
+	FROM categories c
+	INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id AND lm.user_id = $1
+	WHERE ($2 = 0 OR c.ledger_id = $2) AND c.deleted = false
+	ORDER BY c.name ASC, c.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	categories := []*Category{}
+
+	for rows.Next() {
+		category := Category{
+			User:   &User{},
+			Ledger: &Ledger{},
+		}
+		var parentID sql.NullInt64
+
+		err := rows.Scan(
+			&category.ID,
+			&category.CreatedAt,
+			&category.Name,
+			&category.Type,
+			&category.Color,
+			&category.User.ID,
+			&category.Version,
+			&category.Ledger.ID,
+			&category.Ledger.Role,
+			&parentID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		category.Parent = categoryFromNullID(parentID)
+		categories = append(categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildCategoryTree(categories), nil
+}
+
+func BuildCategoryTree(categories []*Category) []*CategoryNode {
+	nodes := make(map[int64]*CategoryNode, len(categories))
+	for _, c := range categories {
+		nodes[c.ID] = &CategoryNode{CategoryDTO: c.ToDTO(), Children: []*CategoryNode{}}
+	}
+
+	roots := []*CategoryNode{}
+	for _, c := range categories {
+		node := nodes[c.ID]
+
+		if c.Parent != nil {
+			if parent, ok := nodes[c.Parent.ID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+
+		roots = append(roots, node)
+	}
+
+	return roots
+}
+
+func nullCategoryID(category *Category) any {
+	if category == nil || category.ID == 0 {
+		return nil
+	}
+	return category.ID
+}
+
+func categoryFromNullID(id sql.NullInt64) *Category {
+	if !id.Valid {
+		return nil
+	}
+	return &Category{ID: id.Int64}
+}
+
+func (m CategoryModel) ExistsByName(name string, ledgerID int64) (bool, error) {
 	query := `
     SELECT EXISTS(
         SELECT 1 
         FROM categories 
         WHERE LOWER(name) = LOWER($1)
-        AND user_id = $2 
+        AND ledger_id = $2
         AND deleted = false
     )`
 
@@ -404,7 +648,7 @@ func (m CategoryModel) ExistsByName(name string, userID int64) (bool, error) {
 	defer cancel()
 
 	var exists bool
-	err := m.DB.QueryRowContext(ctx, query, name, userID).Scan(&exists)
+	err := m.DB.QueryRowContext(ctx, query, name, ledgerID).Scan(&exists)
 
 	if err != nil {
 		return false, err
@@ -419,4 +663,14 @@ func ValidateCategory(v *validator.Validator, category *Category) {
 	v.Check(category.Type.String() != "", "type", "must be provided")
 	v.Check(category.Type.String() != "Unknown", "type", "invalid type")
 	v.Check(category.Color != "", "color", "must be provided")
+
+	if category.Parent != nil {
+		v.Check(category.Parent.ID != category.ID, "parent_id", "must not be the category itself")
+		v.Check(!slices.Contains(category.Parent.Ancestors, category.ID), "parent_id", "must not be one of the category's own subcategories")
+		v.Check(len(category.Parent.Ancestors)+1+category.Height < MaxCategoryDepth, "parent_id", fmt.Sprintf("must not nest categories more than %d levels deep", MaxCategoryDepth))
+
+		if category.Parent.Type != 0 {
+			v.Check(category.Parent.Type == category.Type, "parent_id", "must have the same type as the category")
+		}
+	}
 }
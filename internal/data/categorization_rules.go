@@ -0,0 +1,595 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	MatchAny      = "any"
+	MatchContains = "contains"
+	MatchRegex    = "regex"
+)
+
+var DescriptionMatches = []string{MatchAny, MatchContains, MatchRegex}
+
+type CategorizationRule struct {
+	ID                 int64
+	CreatedAt          time.Time
+	User               *User
+	Category           *Category
+	Name               string
+	Priority           int
+	DescriptionMatch   string
+	DescriptionPattern string
+	MinAmount          *Money
+	MaxAmount          *Money
+	Account            *Account
+	Enabled            bool
+	Deleted            bool
+	Version            int
+
+	re *regexp.Regexp
+}
+
+type CategorizationRuleDTO struct {
+	ID                 *int64       `json:"rule_id"`
+	Version            *int         `json:"version"`
+	Name               *string      `json:"name"`
+	Category           *CategoryDTO `json:"category"`
+	Priority           *int         `json:"priority"`
+	DescriptionMatch   *string      `json:"description_match"`
+	DescriptionPattern *string      `json:"description_pattern"`
+	MinAmount          *Money       `json:"min_amount"`
+	MaxAmount          *Money       `json:"max_amount"`
+	Account            *AccountDTO  `json:"account"`
+	Enabled            *bool        `json:"enabled"`
+	CreatedAt          *time.Time   `json:"created_at"`
+}
+
+type CategoryChange struct {
+	TransactionID  int64  `json:"transaction_id"`
+	Description    string `json:"description"`
+	Amount         Money  `json:"amount"`
+	OccurredOn     Date   `json:"occurred_on"`
+	FromCategoryID int64  `json:"from_category_id"`
+	ToCategoryID   int64  `json:"to_category_id"`
+	RuleID         int64  `json:"rule_id"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+type CategorizationRuleModel struct {
+	DB *sql.DB
+}
+
+func (r *CategorizationRule) ToDTO() *CategorizationRuleDTO {
+	dto := &CategorizationRuleDTO{
+		Priority:           &r.Priority,
+		DescriptionMatch:   &r.DescriptionMatch,
+		DescriptionPattern: &r.DescriptionPattern,
+		MinAmount:          r.MinAmount,
+		MaxAmount:          r.MaxAmount,
+		Enabled:            &r.Enabled,
+	}
+
+	if r.ID != 0 {
+		dto.ID = &r.ID
+	}
+
+	if r.Version != 0 {
+		dto.Version = &r.Version
+	}
+
+	if r.Name != "" {
+		dto.Name = &r.Name
+	}
+
+	if r.Category != nil {
+		dto.Category = r.Category.ToDTO()
+	}
+
+	if r.Account != nil {
+		dto.Account = r.Account.ToDTO()
+	}
+
+	if !r.CreatedAt.IsZero() {
+		dto.CreatedAt = &r.CreatedAt
+	}
+
+	return dto
+}
+
+func (dto *CategorizationRuleDTO) ToModel() *CategorizationRule {
+	rule := &CategorizationRule{
+		Priority:         100,
+		DescriptionMatch: MatchAny,
+		Enabled:          true,
+	}
+
+	dto.ToDTOUpdateRule(rule)
+
+	return rule
+}
+
+func (dto *CategorizationRuleDTO) ToDTOUpdateRule(rule *CategorizationRule) {
+	if dto.Version != nil {
+		rule.Version = *dto.Version
+	}
+
+	if dto.Name != nil {
+		rule.Name = strings.TrimSpace(*dto.Name)
+	}
+
+	if dto.Category != nil {
+		rule.Category = dto.Category.ToModel()
+	}
+
+	if dto.Priority != nil {
+		rule.Priority = *dto.Priority
+	}
+
+	if dto.DescriptionMatch != nil {
+		rule.DescriptionMatch = *dto.DescriptionMatch
+	}
+
+	if dto.DescriptionPattern != nil {
+		rule.DescriptionPattern = *dto.DescriptionPattern
+	}
+
+	if dto.MinAmount != nil {
+		rule.MinAmount = dto.MinAmount
+		if *dto.MinAmount == 0 {
+			rule.MinAmount = nil
+		}
+	}
+
+	if dto.MaxAmount != nil {
+		rule.MaxAmount = dto.MaxAmount
+		if *dto.MaxAmount == 0 {
+			rule.MaxAmount = nil
+		}
+	}
+
+	if dto.Account != nil {
+		rule.Account = dto.Account.ToModel()
+		if rule.Account.ID == 0 {
+			rule.Account = nil
+		}
+	}
+
+	if dto.Enabled != nil {
+		rule.Enabled = *dto.Enabled
+	}
+
+	if rule.DescriptionMatch == MatchAny {
+		rule.DescriptionPattern = ""
+	}
+}
+
+func (r *CategorizationRule) Matches(t *Transaction) bool {
+	switch r.DescriptionMatch {
+	case MatchContains:
+		if !strings.Contains(strings.ToLower(t.Description), strings.ToLower(r.DescriptionPattern)) {
+			return false
+		}
+	case MatchRegex:
+		if r.re == nil {
+			re, err := compileRulePattern(r.DescriptionPattern)
+			if err != nil {
+				return false
+			}
+			r.re = re
+		}
+
+		if !r.re.MatchString(t.Description) {
+			return false
+		}
+	}
+
+	if r.MinAmount != nil && t.Amount < *r.MinAmount {
+		return false
+	}
+
+	if r.MaxAmount != nil && t.Amount > *r.MaxAmount {
+		return false
+	}
+
+	if r.Account != nil && (t.Account == nil || t.Account.ID != r.Account.ID) {
+		return false
+	}
+
+	return true
+}
+
+func MatchCategorizationRule(rules []*CategorizationRule, t *Transaction, categoryType TypeCategoria) *CategorizationRule {
+	for _, rule := range rules {
+		if categoryType != 0 && rule.Category.Type != categoryType {
+			continue
+		}
+
+		if rule.Enabled && rule.Matches(t) {
+			return rule
+		}
+	}
+
+	return nil
+}
+
+func compileRulePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func (m CategorizationRuleModel) Insert(rule *CategorizationRule) error {
+	query := `
+	INSERT INTO categorization_rules (user_id, category_id, name, priority, description_match, description_pattern, min_amount, max_amount, account_id, enabled)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, created_at, version
+	`
+
+	args := []any{
+		rule.User.ID,
+		rule.Category.ID,
+		rule.Name,
+		rule.Priority,
+		rule.DescriptionMatch,
+		rule.DescriptionPattern,
+		rule.MinAmount,
+		rule.MaxAmount,
+		nullAccountID(rule.Account),
+		rule.Enabled,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&rule.ID,
+		&rule.CreatedAt,
+		&rule.Version,
+	)
+}
+
+func (m CategorizationRuleModel) GetByID(id int64, userID int64) (*CategorizationRule, error) {
+	query := `
+	SELECT id, created_at, user_id, category_id, name, priority, description_match, description_pattern,
+		min_amount, max_amount, account_id, enabled, version
+	FROM categorization_rules
+	WHERE id = $1 AND user_id = $2 AND deleted = false
+	`
+
+	rule := CategorizationRule{
+		User:     &User{},
+		Category: &Category{},
+	}
+	var accountID sql.NullInt64
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&rule.ID,
+		&rule.CreatedAt,
+		&rule.User.ID,
+		&rule.Category.ID,
+		&rule.Name,
+		&rule.Priority,
+		&rule.DescriptionMatch,
+		&rule.DescriptionPattern,
+		&rule.MinAmount,
+		&rule.MaxAmount,
+		&accountID,
+		&rule.Enabled,
+		&rule.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	rule.Account = accountFromNullID(accountID)
+
+	return &rule, nil
+}
+
+func (m CategorizationRuleModel) GetAll(userID int64, filters Filters) ([]*CategorizationRule, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, user_id, category_id, name, priority, description_match, description_pattern,
+		min_amount, max_amount, account_id, enabled, version
+	FROM categorization_rules
+	WHERE user_id = $1 AND deleted = false
+	ORDER BY %s %s, id ASC
+	LIMIT $2 OFFSET $3
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	rules := []*CategorizationRule{}
+
+	for rows.Next() {
+		rule := CategorizationRule{
+			User:     &User{},
+			Category: &Category{},
+		}
+		var accountID sql.NullInt64
+
+		err := rows.Scan(
+			&totalRecords,
+			&rule.ID,
+			&rule.CreatedAt,
+			&rule.User.ID,
+			&rule.Category.ID,
+			&rule.Name,
+			&rule.Priority,
+			&rule.DescriptionMatch,
+			&rule.DescriptionPattern,
+			&rule.MinAmount,
+			&rule.MaxAmount,
+			&accountID,
+			&rule.Enabled,
+			&rule.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		rule.Account = accountFromNullID(accountID)
+		rules = append(rules, &rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return rules, metaData, nil
+}
+
+func (m CategorizationRuleModel) GetActive(userID int64) ([]*CategorizationRule, error) {
+	query := `
+	SELECT r.id, r.category_id, r.name, r.priority, r.description_match, r.description_pattern,
+		r.min_amount, r.max_amount, r.account_id, r.enabled, r.version, c.type, c.ledger_id, lm.role
+	FROM categorization_rules r
+	INNER JOIN categories c ON c.id = r.category_id AND c.deleted = false
+	INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id AND lm.user_id = r.user_id AND lm.role <> 'viewer'
+	WHERE r.user_id = $1 AND r.deleted = false AND r.enabled = true
+	ORDER BY r.priority ASC, r.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	rules := []*CategorizationRule{}
+
+	for rows.Next() {
+		rule := CategorizationRule{
+			User:     &User{ID: userID},
+			Category: &Category{Ledger: &Ledger{}},
+		}
+		var accountID sql.NullInt64
+
+		err := rows.Scan(
+			&rule.ID,
+			&rule.Category.ID,
+			&rule.Name,
+			&rule.Priority,
+			&rule.DescriptionMatch,
+			&rule.DescriptionPattern,
+			&rule.MinAmount,
+			&rule.MaxAmount,
+			&accountID,
+			&rule.Enabled,
+			&rule.Version,
+			&rule.Category.Type,
+			&rule.Category.Ledger.ID,
+			&rule.Category.Ledger.Role,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rule.Account = accountFromNullID(accountID)
+		rules = append(rules, &rule)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
+
+func (m CategorizationRuleModel) Update(rule *CategorizationRule, userID int64) error {
+	query := `
+	UPDATE categorization_rules
+	SET category_id = $1,
+		name = $2,
+		priority = $3,
+		description_match = $4,
+		description_pattern = $5,
+		min_amount = $6,
+		max_amount = $7,
+		account_id = $8,
+		enabled = $9,
+		version = version + 1
+	WHERE
+		id = $10
+		AND user_id = $11
+		AND deleted = false
+		AND version = $12
+	RETURNING version
+	`
+
+	args := []any{
+		rule.Category.ID,
+		rule.Name,
+		rule.Priority,
+		rule.DescriptionMatch,
+		rule.DescriptionPattern,
+		rule.MinAmount,
+		rule.MaxAmount,
+		nullAccountID(rule.Account),
+		rule.Enabled,
+		rule.ID,
+		userID,
+		rule.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&rule.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m CategorizationRuleModel) Delete(id int64, userID int64) error {
+	query := `
+	UPDATE categorization_rules
+	SET
+		deleted = true
+	WHERE
+		id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m CategorizationRuleModel) ApplyChanges(changes []*CategoryChange, userID int64) (int, error) {
+	query := `
+	UPDATE transactions t
+	SET category_id = $1, version = version + 1
+	FROM categories nc, categories oc
+	WHERE t.id = $2 AND t.user_id = $3 AND t.category_id = $4 AND t.deleted = false
+	AND nc.id = $1 AND oc.id = $4 AND nc.type = oc.type
+	AND (
+		NOT EXISTS (SELECT 1 FROM transaction_splits s WHERE s.transaction_id = t.id)
+		OR (nc.type = $5 AND nc.ledger_id = oc.ledger_id)
+	)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	updated := 0
+	for _, change := range changes {
+		result, err := stmt.ExecContext(ctx, change.ToCategoryID, change.TransactionID, userID, change.FromCategoryID, DESPESA)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+
+		updated += int(rowsAffected)
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return updated, nil
+}
+
+func ValidateCategorizationRule(v *validator.Validator, rule *CategorizationRule) {
+	v.Check(rule.Name != "", "name", "must be provided")
+	v.Check(len(rule.Name) <= 100, "name", "must not be more than 100 bytes long")
+	v.Check(rule.Category != nil && rule.Category.ID > 0, "category", "must be provided")
+	v.Check(rule.Priority >= 0 && rule.Priority <= 10000, "priority", "must be between 0 and 10000")
+	v.Check(validator.In(rule.DescriptionMatch, DescriptionMatches...), "description_match", "must be one of any, contains or regex")
+
+	if rule.DescriptionMatch != MatchAny {
+		v.Check(strings.TrimSpace(rule.DescriptionPattern) != "", "description_pattern", "must be provided")
+		v.Check(len(rule.DescriptionPattern) <= 500, "description_pattern", "must not be more than 500 bytes long")
+	}
+
+	if rule.DescriptionMatch == MatchRegex {
+		_, err := compileRulePattern(rule.DescriptionPattern)
+		v.Check(err == nil, "description_pattern", "must be a valid regular expression")
+	}
+
+	if rule.MinAmount != nil {
+		v.Check(*rule.MinAmount > 0, "min_amount", "must be positive")
+		v.Check(*rule.MinAmount <= MaxMoney, "min_amount", "must not be more than 9999999999999.99")
+	}
+
+	if rule.MaxAmount != nil {
+		v.Check(*rule.MaxAmount > 0, "max_amount", "must be positive")
+		v.Check(*rule.MaxAmount <= MaxMoney, "max_amount", "must not be more than 9999999999999.99")
+	}
+
+	if rule.MinAmount != nil && rule.MaxAmount != nil {
+		v.Check(*rule.MinAmount <= *rule.MaxAmount, "max_amount", "must not be less than min_amount")
+	}
+
+	v.Check(rule.DescriptionMatch != MatchAny || rule.MinAmount != nil || rule.MaxAmount != nil || rule.Account != nil,
+		"description_match", "a rule must have at least one condition on description, amount or account")
+}
@@ -0,0 +1,246 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"meus_gastos/internal/validator"
+	"strings"
+	"time"
+)
+
+const DefaultCategoryTemplate = "basico"
+
+type CategoryTemplate struct {
+	Name     string              `json:"name"`
+	Type     string              `json:"type"`
+	Color    string              `json:"color"`
+	Children []*CategoryTemplate `json:"children,omitempty"`
+}
+
+type CategoryTemplatePack struct {
+	Code        string              `json:"code"`
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Categories  []*CategoryTemplate `json:"categories"`
+}
+
+var DefaultCategoryTemplates = []*CategoryTemplatePack{
+	{
+		Code:        "basico",
+		Name:        "Básico",
+		Description: "Categorias essenciais para o controle do dia a dia.",
+		Categories: []*CategoryTemplate{
+			{Name: "Salário", Type: "RECEITA", Color: "#2E7D32"},
+			{Name: "Outras receitas", Type: "RECEITA", Color: "#66BB6A"},
+			{Name: "Alimentação", Type: "DESPESA", Color: "#EF6C00"},
+			{Name: "Moradia", Type: "DESPESA", Color: "#5D4037"},
+			{Name: "Transporte", Type: "DESPESA", Color: "#1565C0"},
+			{Name: "Saúde", Type: "DESPESA", Color: "#C62828"},
+			{Name: "Educação", Type: "DESPESA", Color: "#6A1B9A"},
+			{Name: "Lazer", Type: "DESPESA", Color: "#F9A825"},
+			{Name: "Outras despesas", Type: "DESPESA", Color: "#757575"},
+		},
+	},
+	{
+		Code:        "familia",
+		Name:        "Família",
+		Description: "Orçamento doméstico detalhado, com subcategorias para a casa e os filhos.",
+		Categories: []*CategoryTemplate{
+			{Name: "Salário", Type: "RECEITA", Color: "#2E7D32"},
+			{Name: "Benefícios", Type: "RECEITA", Color: "#43A047"},
+			{Name: "Alimentação", Type: "DESPESA", Color: "#EF6C00", Children: []*CategoryTemplate{
+				{Name: "Supermercado", Type: "DESPESA", Color: "#FB8C00"},
+				{Name: "Restaurantes", Type: "DESPESA", Color: "#FFA726"},
+			}},
+			{Name: "Moradia", Type: "DESPESA", Color: "#5D4037", Children: []*CategoryTemplate{
+				{Name: "Aluguel", Type: "DESPESA", Color: "#6D4C41"},
+				{Name: "Energia", Type: "DESPESA", Color: "#795548"},
+				{Name: "Água", Type: "DESPESA", Color: "#8D6E63"},
+				{Name: "Internet", Type: "DESPESA", Color: "#A1887F"},
+			}},
+			{Name: "Filhos", Type: "DESPESA", Color: "#AD1457", Children: []*CategoryTemplate{
+				{Name: "Escola", Type: "DESPESA", Color: "#C2185B"},
+				{Name: "Atividades", Type: "DESPESA", Color: "#D81B60"},
+			}},
+			{Name: "Transporte", Type: "DESPESA", Color: "#1565C0"},
+			{Name: "Saúde", Type: "DESPESA", Color: "#C62828"},
+			{Name: "Lazer", Type: "DESPESA", Color: "#F9A825"},
+		},
+	},
+	{
+		Code:        "autonomo",
+		Name:        "Autônomo",
+		Description: "Para quem trabalha por conta própria e separa receitas e custos do trabalho.",
+		Categories: []*CategoryTemplate{
+			{Name: "Serviços prestados", Type: "RECEITA", Color: "#2E7D32"},
+			{Name: "Vendas", Type: "RECEITA", Color: "#43A047"},
+			{Name: "Impostos", Type: "DESPESA", Color: "#B71C1C"},
+			{Name: "Ferramentas e assinaturas", Type: "DESPESA", Color: "#283593"},
+			{Name: "Equipamentos", Type: "DESPESA", Color: "#37474F"},
+			{Name: "Alimentação", Type: "DESPESA", Color: "#EF6C00"},
+			{Name: "Moradia", Type: "DESPESA", Color: "#5D4037"},
+			{Name: "Transporte", Type: "DESPESA", Color: "#1565C0"},
+		},
+	},
+}
+
+func FindCategoryTemplatePack(packs []*CategoryTemplatePack, code string) *CategoryTemplatePack {
+	for _, pack := range packs {
+		if pack.Code == code {
+			return pack
+		}
+	}
+
+	return nil
+}
+
+func ReadCategoryTemplatesJSON(r io.Reader) ([]*CategoryTemplatePack, error) {
+	var packs []*CategoryTemplatePack
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(&packs)
+	if err != nil {
+		return nil, err
+	}
+
+	v := validator.New()
+	for _, pack := range packs {
+		ValidateCategoryTemplatePack(v, pack)
+	}
+
+	seen := make(map[string]bool, len(packs))
+	for _, pack := range packs {
+		v.Check(!seen[pack.Code], "code", "must be unique")
+		seen[pack.Code] = true
+	}
+
+	if !v.Valid() {
+		for key, message := range v.Errors {
+			return nil, fmt.Errorf("invalid category template %s: %s", key, message)
+		}
+	}
+
+	return packs, nil
+}
+
+type SkippedCategoryTemplate struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	CategoryID int64  `json:"category_id"`
+	Reason     string `json:"reason"`
+}
+
+func (m CategoryModel) ApplyTemplate(pack *CategoryTemplatePack, userID int64, ledgerID int64) ([]*Category, []*SkippedCategoryTemplate, error) {
+	findQuery := `
+	SELECT id, type
+	FROM categories
+	WHERE LOWER(name) = LOWER($1) AND ledger_id = $2 AND deleted = false
+	`
+
+	insertQuery := `
+	INSERT INTO categories (name, type, color, user_id, ledger_id, parent_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	created := []*Category{}
+	skipped := []*SkippedCategoryTemplate{}
+
+	var apply func(templates []*CategoryTemplate, parent *Category) error
+	apply = func(templates []*CategoryTemplate, parent *Category) error {
+		for _, t := range templates {
+			category := &Category{
+				Name:   t.Name,
+				Type:   TypeCategoriaFromString(t.Type),
+				Color:  t.Color,
+				User:   &User{ID: userID},
+				Ledger: &Ledger{ID: ledgerID},
+				Parent: parent,
+			}
+
+			var existingType TypeCategoria
+			err := tx.QueryRowContext(ctx, findQuery, t.Name, ledgerID).Scan(&category.ID, &existingType)
+			switch {
+			case err == nil && existingType != category.Type:
+				skipped = append(skipped, &SkippedCategoryTemplate{
+					Name:       t.Name,
+					Type:       t.Type,
+					CategoryID: category.ID,
+					Reason:     fmt.Sprintf("a %s category with this name already exists", existingType),
+				})
+				continue
+			case errors.Is(err, sql.ErrNoRows):
+				args := []any{category.Name, category.Type, category.Color, userID, ledgerID, nullCategoryID(parent)}
+				err = tx.QueryRowContext(ctx, insertQuery, args...).Scan(&category.ID, &category.CreatedAt, &category.Version)
+				if err != nil {
+					return err
+				}
+				created = append(created, category)
+			case err != nil:
+				return err
+			}
+
+			err = apply(t.Children, &Category{ID: category.ID})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	err = apply(pack.Categories, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return created, skipped, nil
+}
+
+func ValidateCategoryTemplatePack(v *validator.Validator, pack *CategoryTemplatePack) {
+	v.Check(pack.Code != "", "code", "must be provided")
+	v.Check(pack.Name != "", "name", "must be provided")
+	v.Check(len(pack.Categories) > 0, "categories", "must contain at least one category")
+
+	seen := make(map[string]bool)
+	var check func(templates []*CategoryTemplate, parentType string, depth int)
+	check = func(templates []*CategoryTemplate, parentType string, depth int) {
+		v.Check(depth < MaxCategoryDepth, "categories", fmt.Sprintf("must not nest categories more than %d levels deep", MaxCategoryDepth))
+
+		for _, t := range templates {
+			name := strings.ToLower(t.Name)
+			v.Check(t.Name != "", "categories", "names must be provided")
+			v.Check(len(t.Name) <= 500, "categories", "names must not be more than 500 bytes long")
+			v.Check(!seen[name], "categories", "names must be unique within a template")
+			v.Check(TypeCategoriaFromString(t.Type) != 0, "categories", "type must be RECEITA or DESPESA")
+			v.Check(t.Color != "", "categories", "color must be provided")
+			if parentType != "" {
+				v.Check(t.Type == parentType, "categories", "subcategories must have the same type as their parent")
+			}
+			seen[name] = true
+
+			check(t.Children, t.Type, depth+1)
+		}
+	}
+	check(pack.Categories, "", 0)
+}
@@ -0,0 +1,85 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+const DateLayout = "2006-01-02"
+
+var ErrInvalidDateFormat = errors.New("invalid date format, expected YYYY-MM-DD")
+
+type Date struct {
+	time.Time
+}
+
+func NewDate(t time.Time) Date {
+	return Date{time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)}
+}
+
+func Today() Date {
+	return NewDate(time.Now())
+}
+
+func (d Date) String() string {
+	return d.Format(DateLayout)
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+func (d *Date) UnmarshalJSON(jsonValue []byte) error {
+	unquoted, err := strconv.Unquote(string(jsonValue))
+	if err != nil {
+		return ErrInvalidDateFormat
+	}
+
+	t, err := time.Parse(DateLayout, unquoted)
+	if err != nil {
+		return ErrInvalidDateFormat
+	}
+
+	*d = NewDate(t)
+	return nil
+}
+
+func (d *Date) Scan(value any) error {
+	switch v := value.(type) {
+	case time.Time:
+		*d = NewDate(v)
+		return nil
+	case string:
+		t, err := time.Parse(DateLayout, v)
+		if err != nil {
+			return err
+		}
+		*d = NewDate(t)
+		return nil
+	case []byte:
+		return d.Scan(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+}
+
+func (d Date) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d Date) nullable() any {
+	if d.IsZero() {
+		return nil
+	}
+	return d
+}
+
+func nullDate(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return NewDate(*t)
+}
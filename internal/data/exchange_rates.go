@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"meus_gastos/internal/validator"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DefaultCurrency = "BRL"
+
+var (
+	CurrencyRX = regexp.MustCompile("^[A-Z]{3}$")
+)
+
+type ExchangeRate struct {
+	Base  string  `json:"base"`
+	Quote string  `json:"quote"`
+	Date  Date    `json:"date"`
+	Rate  float64 `json:"rate"`
+}
+
+type ExchangeRateModel struct {
+	DB *sql.DB
+}
+
+func (m ExchangeRateModel) Upsert(rates []*ExchangeRate) error {
+	query := `
+	INSERT INTO exchange_rates (base_currency, quote_currency, rate_date, rate)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (base_currency, quote_currency, rate_date) DO UPDATE SET rate = EXCLUDED.rate
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, rate := range rates {
+		_, err = stmt.ExecContext(ctx, rate.Base, rate.Quote, rate.Date, rate.Rate)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m ExchangeRateModel) GetAll(base, quote string, filters Filters) ([]*ExchangeRate, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), base_currency, quote_currency, rate_date, rate
+	FROM exchange_rates
+	WHERE (base_currency = $1 OR $1 = '')
+	AND (quote_currency = $2 OR $2 = '')
+	ORDER BY %s %s, base_currency ASC, quote_currency ASC
+	LIMIT $3 OFFSET $4
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, base, quote, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	rates := []*ExchangeRate{}
+
+	for rows.Next() {
+		var rate ExchangeRate
+
+		err := rows.Scan(
+			&totalRecords,
+			&rate.Base,
+			&rate.Quote,
+			&rate.Date,
+			&rate.Rate,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		rates = append(rates, &rate)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return rates, metaData, nil
+}
+
+func ReadExchangeRatesCSV(r io.Reader) ([]*ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+
+	rates := []*ExchangeRate{}
+	line := 0
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		line++
+
+		if err != nil {
+			return nil, err
+		}
+
+		if line == 1 && strings.EqualFold(record[0], "base") {
+			continue
+		}
+
+		date, err := time.Parse(DateLayout, record[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, ErrInvalidDateFormat)
+		}
+
+		value, err := strconv.ParseFloat(record[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid rate %q", line, record[3])
+		}
+
+		rate := &ExchangeRate{
+			Base:  strings.ToUpper(record[0]),
+			Quote: strings.ToUpper(record[1]),
+			Date:  NewDate(date),
+			Rate:  value,
+		}
+
+		v := validator.New()
+		if ValidateExchangeRate(v, "rate", rate); !v.Valid() {
+			return nil, fmt.Errorf("line %d: %v", line, v.Errors)
+		}
+
+		rates = append(rates, rate)
+	}
+
+	return rates, nil
+}
+
+func ValidateCurrency(v *validator.Validator, key, currency string) {
+	v.Check(currency != "", key, "must be provided")
+	v.Check(validator.Matches(currency, CurrencyRX), key, "must be a 3-letter ISO 4217 code")
+}
+
+func ValidateExchangeRate(v *validator.Validator, key string, rate *ExchangeRate) {
+	ValidateCurrency(v, key+".base", rate.Base)
+	ValidateCurrency(v, key+".quote", rate.Quote)
+	v.Check(rate.Base != rate.Quote, key+".quote", "must be different from base")
+	v.Check(!rate.Date.IsZero(), key+".date", "must be provided")
+	v.Check(rate.Rate > 0, key+".rate", "must be positive")
+}
@@ -0,0 +1,426 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+const MaxInstallments = 48
+
+type InstallmentPurchase struct {
+	ID           int64
+	CreatedAt    time.Time
+	User         *User
+	Account      *Account
+	Category     *Category
+	Description  string
+	Amount       Money
+	Installments int
+	OccurredOn   Date
+	Transactions []*Transaction
+	Deleted      bool
+	Version      int
+}
+
+type InstallmentPurchaseDTO struct {
+	ID           *int64            `json:"installment_purchase_id"`
+	Version      *int              `json:"version"`
+	Account      *AccountDTO       `json:"account"`
+	Category     *CategoryDTO      `json:"category"`
+	Description  *string           `json:"description"`
+	Amount       *Money            `json:"amount"`
+	Installments *int              `json:"installments"`
+	OccurredOn   *Date             `json:"occurred_on"`
+	Transactions []*TransactionDTO `json:"transactions,omitempty"`
+	CreatedAt    *time.Time        `json:"created_at"`
+}
+
+type InstallmentPurchaseModel struct {
+	DB *sql.DB
+}
+
+func (p *InstallmentPurchase) ToDTO() *InstallmentPurchaseDTO {
+	dto := &InstallmentPurchaseDTO{}
+
+	if p.ID != 0 {
+		dto.ID = &p.ID
+	}
+
+	if p.Version != 0 {
+		dto.Version = &p.Version
+	}
+
+	if p.Account != nil {
+		dto.Account = p.Account.ToDTO()
+	}
+
+	if p.Category != nil {
+		dto.Category = p.Category.ToDTO()
+	}
+
+	if p.Description != "" {
+		dto.Description = &p.Description
+	}
+
+	if p.Amount != 0 {
+		dto.Amount = &p.Amount
+	}
+
+	if p.Installments != 0 {
+		dto.Installments = &p.Installments
+	}
+
+	if !p.OccurredOn.IsZero() {
+		dto.OccurredOn = &p.OccurredOn
+	}
+
+	for _, t := range p.Transactions {
+		dto.Transactions = append(dto.Transactions, t.ToDTO())
+	}
+
+	dto.CreatedAt = &p.CreatedAt
+
+	return dto
+}
+
+func (dto *InstallmentPurchaseDTO) ToModel() *InstallmentPurchase {
+	purchase := &InstallmentPurchase{}
+
+	if dto.ID != nil {
+		purchase.ID = *dto.ID
+	}
+	if dto.Version != nil {
+		purchase.Version = *dto.Version
+	}
+	if dto.Account != nil {
+		purchase.Account = dto.Account.ToModel()
+	}
+	if dto.Category != nil {
+		purchase.Category = dto.Category.ToModel()
+	}
+	if dto.Description != nil {
+		purchase.Description = *dto.Description
+	}
+	if dto.Amount != nil {
+		purchase.Amount = *dto.Amount
+	}
+	if dto.Installments != nil {
+		purchase.Installments = *dto.Installments
+	}
+	if dto.OccurredOn != nil {
+		purchase.OccurredOn = *dto.OccurredOn
+	}
+
+	return purchase
+}
+
+func (p *InstallmentPurchase) Schedule() []*Transaction {
+	n := p.Installments
+	share := p.Amount / Money(n)
+	remainder := p.Amount - share*Money(n)
+
+	closing := p.Account.InvoiceClosing(p.OccurredOn)
+	transactions := make([]*Transaction, 0, n)
+
+	for k := 0; k < n; k++ {
+		occurredOn := p.OccurredOn
+		if k > 0 {
+			occurredOn = NewDate(p.Account.ShiftInvoice(closing, k-1).AddDate(0, 0, 1))
+		}
+
+		amount := share
+		if k == 0 {
+			amount += remainder
+		}
+
+		transactions = append(transactions, &Transaction{
+			User:        p.User,
+			Category:    p.Category,
+			Account:     p.Account,
+			Description: fmt.Sprintf("%s (%d/%d)", p.Description, k+1, n),
+			Amount:      amount,
+			Currency:    p.Account.Currency,
+			OccurredOn:  occurredOn,
+		})
+	}
+
+	return transactions
+}
+
+func (m InstallmentPurchaseModel) Insert(purchase *InstallmentPurchase) error {
+	query := `
+	INSERT INTO installment_purchases (user_id, account_id, category_id, description, amount, installments, occurred_on)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)
+	RETURNING id, created_at, version
+	`
+
+	transactionQuery := `
+	INSERT INTO transactions (user_id, category_id, account_id, description, amount, occurred_on, currency, installment_purchase_id, installment_number)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := []any{
+		purchase.User.ID,
+		purchase.Account.ID,
+		purchase.Category.ID,
+		purchase.Description,
+		purchase.Amount,
+		purchase.Installments,
+		purchase.OccurredOn,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&purchase.ID,
+		&purchase.CreatedAt,
+		&purchase.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	purchase.Transactions = purchase.Schedule()
+
+	for i, transaction := range purchase.Transactions {
+		err = tx.QueryRowContext(ctx, transactionQuery,
+			transaction.User.ID,
+			transaction.Category.ID,
+			transaction.Account.ID,
+			transaction.Description,
+			transaction.Amount,
+			transaction.OccurredOn,
+			transaction.Currency,
+			purchase.ID,
+			i+1,
+		).Scan(
+			&transaction.ID,
+			&transaction.CreatedAt,
+			&transaction.Version,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m InstallmentPurchaseModel) GetByID(id int64, userID int64) (*InstallmentPurchase, error) {
+	query := `
+	SELECT id, created_at, user_id, account_id, category_id, description, amount, installments, occurred_on, deleted, version
+	FROM installment_purchases
+	WHERE id = $1 AND user_id = $2 AND deleted = false
+	`
+
+	transactionsQuery := `
+	SELECT id, created_at, version, description, amount, currency, occurred_on
+	FROM transactions
+	WHERE installment_purchase_id = $1 AND user_id = $2 AND deleted = false
+	ORDER BY installment_number ASC
+	`
+
+	purchase := InstallmentPurchase{
+		User:     &User{},
+		Account:  &Account{},
+		Category: &Category{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&purchase.ID,
+		&purchase.CreatedAt,
+		&purchase.User.ID,
+		&purchase.Account.ID,
+		&purchase.Category.ID,
+		&purchase.Description,
+		&purchase.Amount,
+		&purchase.Installments,
+		&purchase.OccurredOn,
+		&purchase.Deleted,
+		&purchase.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	rows, err := m.DB.QueryContext(ctx, transactionsQuery, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction := Transaction{
+			Category: purchase.Category,
+			Account:  purchase.Account,
+		}
+
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.CreatedAt,
+			&transaction.Version,
+			&transaction.Description,
+			&transaction.Amount,
+			&transaction.Currency,
+			&transaction.OccurredOn,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		purchase.Transactions = append(purchase.Transactions, &transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &purchase, nil
+}
+
+func (m InstallmentPurchaseModel) GetAll(userID int64, accountID int64, filters Filters) ([]*InstallmentPurchase, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, user_id, account_id, category_id, description, amount, installments, occurred_on, deleted, version
+	FROM installment_purchases
+	WHERE user_id = $1 AND deleted = false
+	AND ($2 = 0 OR account_id = $2)
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, accountID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	purchases := []*InstallmentPurchase{}
+
+	for rows.Next() {
+		purchase := InstallmentPurchase{
+			User:     &User{},
+			Account:  &Account{},
+			Category: &Category{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&purchase.ID,
+			&purchase.CreatedAt,
+			&purchase.User.ID,
+			&purchase.Account.ID,
+			&purchase.Category.ID,
+			&purchase.Description,
+			&purchase.Amount,
+			&purchase.Installments,
+			&purchase.OccurredOn,
+			&purchase.Deleted,
+			&purchase.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		purchases = append(purchases, &purchase)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return purchases, metaData, nil
+}
+
+func (m InstallmentPurchaseModel) Delete(id int64, userID int64) error {
+	query := `
+	UPDATE installment_purchases
+	SET
+		deleted = true
+	WHERE
+		id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	transactionsQuery := `
+	UPDATE transactions
+	SET
+		deleted = true
+	WHERE
+		installment_purchase_id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, transactionsQuery, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ValidateInstallmentPurchase(v *validator.Validator, purchase *InstallmentPurchase) {
+	v.Check(purchase.User != nil, "user", "must be provided")
+	v.Check(purchase.Account != nil && purchase.Account.ID > 0, "account", "must be provided")
+	v.Check(purchase.Category != nil && purchase.Category.ID > 0, "category", "must be provided")
+	v.Check(purchase.Description != "", "description", "must be provided")
+	v.Check(len(purchase.Description) <= 490, "description", "must not be more than 490 bytes long")
+	v.Check(purchase.Amount > 0, "amount", "must be positive")
+	v.Check(purchase.Amount <= MaxMoney, "amount", "must not be more than 9999999999999.99")
+	v.Check(purchase.Installments >= 1, "installments", "must be at least 1")
+	v.Check(purchase.Installments <= MaxInstallments, "installments", fmt.Sprintf("must not be more than %d", MaxInstallments))
+	v.Check(!purchase.OccurredOn.IsZero(), "occurred_on", "must be provided")
+	v.Check(purchase.Installments == 0 || purchase.Amount >= Money(purchase.Installments), "amount", "must be at least 0.01 per installment")
+}
@@ -0,0 +1,96 @@
+package data
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestInstallmentPurchaseSchedule(t *testing.T) {
+	tests := []struct {
+		name         string
+		closingDay   int
+		occurredOn   string
+		amount       Money
+		installments int
+		wantDates    []string
+		wantAmounts  []Money
+	}{
+		{
+			name:         "remainder on first installment",
+			closingDay:   25,
+			occurredOn:   "2024-01-20",
+			amount:       10000,
+			installments: 3,
+			wantDates:    []string{"2024-01-20", "2024-01-26", "2024-02-26"},
+			wantAmounts:  []Money{3334, 3333, 3333},
+		},
+		{
+			name:         "purchase after closing",
+			closingDay:   10,
+			occurredOn:   "2024-11-15",
+			amount:       5000,
+			installments: 3,
+			wantDates:    []string{"2024-11-15", "2024-12-11", "2025-01-11"},
+			wantAmounts:  []Money{1668, 1666, 1666},
+		},
+		{
+			name:         "month end closing",
+			closingDay:   31,
+			occurredOn:   "2024-01-31",
+			amount:       1000,
+			installments: 4,
+			wantDates:    []string{"2024-01-31", "2024-02-01", "2024-03-01", "2024-04-01"},
+			wantAmounts:  []Money{250, 250, 250, 250},
+		},
+		{
+			name:         "single installment",
+			closingDay:   5,
+			occurredOn:   "2024-03-01",
+			amount:       1,
+			installments: 1,
+			wantDates:    []string{"2024-03-01"},
+			wantAmounts:  []Money{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			purchase := &InstallmentPurchase{
+				User:         &User{ID: 1},
+				Account:      creditCard(tt.closingDay, 5),
+				Category:     &Category{ID: 1},
+				Description:  "TV",
+				Amount:       tt.amount,
+				Installments: tt.installments,
+				OccurredOn:   date(tt.occurredOn),
+			}
+
+			schedule := purchase.Schedule()
+			if len(schedule) != tt.installments {
+				t.Fatalf("got %d transactions, want %d", len(schedule), tt.installments)
+			}
+
+			var total Money
+			for i, transaction := range schedule {
+				if got := transaction.OccurredOn.String(); got != tt.wantDates[i] {
+					t.Errorf("installment %d: got date %s, want %s", i+1, got, tt.wantDates[i])
+				}
+				if transaction.Amount != tt.wantAmounts[i] {
+					t.Errorf("installment %d: got amount %s, want %s", i+1, transaction.Amount, tt.wantAmounts[i])
+				}
+				if transaction.Currency != "BRL" {
+					t.Errorf("installment %d: got currency %q, want BRL", i+1, transaction.Currency)
+				}
+				total += transaction.Amount
+			}
+
+			if total != tt.amount {
+				t.Errorf("installments add up to %s, want %s", total, tt.amount)
+			}
+
+			if want := fmt.Sprintf("TV (%d/%d)", tt.installments, tt.installments); schedule[tt.installments-1].Description != want {
+				t.Errorf("got description %q, want %q", schedule[tt.installments-1].Description, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,620 @@
+package data
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"strings"
+	"time"
+)
+
+type LedgerRole string
+
+const (
+	LedgerOwner  LedgerRole = "owner"
+	LedgerEditor LedgerRole = "editor"
+	LedgerViewer LedgerRole = "viewer"
+)
+
+const DefaultLedgerName = "Pessoal"
+
+var (
+	ErrAlreadyLedgerMember = errors.New("already a ledger member")
+)
+
+func (r LedgerRole) CanWrite() bool {
+	return r == LedgerOwner || r == LedgerEditor
+}
+
+func (r LedgerRole) CanManage() bool {
+	return r == LedgerOwner
+}
+
+type Ledger struct {
+	ID        int64
+	CreatedAt time.Time
+	Name      string
+	Owner     *User
+	Role      LedgerRole
+	Version   int
+}
+
+type LedgerDTO struct {
+	ID        *int64     `json:"ledger_id"`
+	Version   *int       `json:"version,omitempty"`
+	Name      *string    `json:"name,omitempty"`
+	Owner     *UserDTO   `json:"owner,omitempty"`
+	Role      *string    `json:"role,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+type LedgerMember struct {
+	User      *User
+	Role      LedgerRole
+	CreatedAt time.Time
+}
+
+type LedgerMemberDTO struct {
+	User     *UserDTO   `json:"user"`
+	Role     LedgerRole `json:"role"`
+	JoinedAt time.Time  `json:"joined_at"`
+}
+
+type LedgerInvitation struct {
+	ID         int64      `json:"invitation_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LedgerID   int64      `json:"ledger_id"`
+	Email      string     `json:"email"`
+	Role       LedgerRole `json:"role"`
+	InvitedBy  int64      `json:"invited_by"`
+	Expiry     time.Time  `json:"expiry"`
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	Plaintext  string     `json:"-"`
+}
+
+type LedgerModel struct {
+	DB *sql.DB
+}
+
+func (l *Ledger) ToDTO() *LedgerDTO {
+	dto := &LedgerDTO{
+		ID: &l.ID,
+	}
+
+	if l.Version != 0 {
+		dto.Version = &l.Version
+	}
+
+	if l.Name != "" {
+		dto.Name = &l.Name
+	}
+
+	if l.Owner != nil && l.Owner.Email != "" {
+		dto.Owner = l.Owner.ToDTO()
+	}
+
+	if l.Role != "" {
+		role := string(l.Role)
+		dto.Role = &role
+	}
+
+	if !l.CreatedAt.IsZero() {
+		dto.CreatedAt = &l.CreatedAt
+	}
+
+	return dto
+}
+
+func (dto *LedgerDTO) ToModel() *Ledger {
+	ledger := &Ledger{}
+
+	if dto.ID != nil {
+		ledger.ID = *dto.ID
+	}
+
+	if dto.Version != nil {
+		ledger.Version = *dto.Version
+	}
+
+	if dto.Name != nil {
+		ledger.Name = strings.TrimSpace(*dto.Name)
+	}
+
+	return ledger
+}
+
+func (m *LedgerMember) ToDTO() *LedgerMemberDTO {
+	return &LedgerMemberDTO{
+		User:     m.User.ToDTO(),
+		Role:     m.Role,
+		JoinedAt: m.CreatedAt,
+	}
+}
+
+func (m LedgerModel) Insert(ledger *Ledger, ownerID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertLedger(ctx, tx, ledger, ownerID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertLedger(ctx context.Context, tx *sql.Tx, ledger *Ledger, ownerID int64) error {
+	query := `
+	INSERT INTO ledgers (name, owner_id)
+	VALUES ($1, $2)
+	RETURNING id, created_at, version
+	`
+
+	memberQuery := `
+	INSERT INTO ledger_members (ledger_id, user_id, role)
+	VALUES ($1, $2, $3)
+	`
+
+	err := tx.QueryRowContext(ctx, query, ledger.Name, ownerID).Scan(
+		&ledger.ID,
+		&ledger.CreatedAt,
+		&ledger.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, memberQuery, ledger.ID, ownerID, LedgerOwner)
+	if err != nil {
+		return err
+	}
+
+	ledger.Owner = &User{ID: ownerID}
+	ledger.Role = LedgerOwner
+
+	return nil
+}
+
+func (m LedgerModel) GetByID(id int64, userID int64) (*Ledger, error) {
+	query := `
+	SELECT l.id, l.created_at, l.name, l.version, lm.role, u.id, u.name, u.email, u.phone
+	FROM ledgers l
+	INNER JOIN ledger_members lm ON lm.ledger_id = l.id AND lm.user_id = $2
+	INNER JOIN users u ON u.id = l.owner_id
+	WHERE l.id = $1
+	`
+
+	ledger := Ledger{
+		Owner: &User{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&ledger.ID,
+		&ledger.CreatedAt,
+		&ledger.Name,
+		&ledger.Version,
+		&ledger.Role,
+		&ledger.Owner.ID,
+		&ledger.Owner.Name,
+		&ledger.Owner.Email,
+		&ledger.Owner.Phone,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &ledger, nil
+}
+
+func (m LedgerModel) GetDefaultForUser(userID int64) (*Ledger, error) {
+	query := `
+	SELECT id
+	FROM ledgers
+	WHERE owner_id = $1
+	ORDER BY id ASC
+	LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var id int64
+	err := m.DB.QueryRowContext(ctx, query, userID).Scan(&id)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return m.GetByID(id, userID)
+}
+
+func (m LedgerModel) GetAll(userID int64, filters Filters) ([]*Ledger, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), l.id, l.created_at, l.name, l.version, lm.role, u.id, u.name, u.email, u.phone
+	FROM ledgers l
+	INNER JOIN ledger_members lm ON lm.ledger_id = l.id AND lm.user_id = $1
+	INNER JOIN users u ON u.id = l.owner_id
+	ORDER BY l.%s %s, l.id ASC
+	LIMIT $2 OFFSET $3
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	ledgers := []*Ledger{}
+
+	for rows.Next() {
+		ledger := Ledger{
+			Owner: &User{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&ledger.ID,
+			&ledger.CreatedAt,
+			&ledger.Name,
+			&ledger.Version,
+			&ledger.Role,
+			&ledger.Owner.ID,
+			&ledger.Owner.Name,
+			&ledger.Owner.Email,
+			&ledger.Owner.Phone,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		ledgers = append(ledgers, &ledger)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return ledgers, metaData, nil
+}
+
+func (m LedgerModel) Update(ledger *Ledger) error {
+	query := `
+	UPDATE ledgers
+	SET name = $1, version = version + 1
+	WHERE id = $2 AND version = $3
+	RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, ledger.Name, ledger.ID, ledger.Version).Scan(&ledger.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m LedgerModel) GetMembers(ledgerID int64) ([]*LedgerMember, error) {
+	query := `
+	SELECT u.id, u.name, u.email, u.phone, lm.role, lm.created_at
+	FROM ledger_members lm
+	INNER JOIN users u ON u.id = lm.user_id
+	WHERE lm.ledger_id = $1
+	ORDER BY lm.created_at ASC, u.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	members := []*LedgerMember{}
+
+	for rows.Next() {
+		member := LedgerMember{
+			User: &User{},
+		}
+
+		err := rows.Scan(
+			&member.User.ID,
+			&member.User.Name,
+			&member.User.Email,
+			&member.User.Phone,
+			&member.Role,
+			&member.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		members = append(members, &member)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+func (m LedgerModel) UpdateMemberRole(ledgerID int64, userID int64, role LedgerRole) error {
+	query := `
+	UPDATE ledger_members
+	SET role = $1
+	WHERE ledger_id = $2 AND user_id = $3 AND role <> 'owner'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, role, ledgerID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m LedgerModel) RemoveMember(ledgerID int64, userID int64) error {
+	query := `
+	DELETE FROM ledger_members
+	WHERE ledger_id = $1 AND user_id = $2 AND role <> 'owner'
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, ledgerID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m LedgerModel) InsertInvitation(invitation *LedgerInvitation, ttl time.Duration) error {
+	query := `
+	INSERT INTO ledger_invitations (ledger_id, email, role, invited_by, hash, expiry)
+	SELECT $1, $2, $3, $4, $5, $6
+	WHERE NOT EXISTS (
+		SELECT 1
+		FROM ledger_members lm
+		INNER JOIN users u ON u.id = lm.user_id
+		WHERE lm.ledger_id = $1 AND u.email = $2 AND u.deleted = false
+	)
+	ON CONFLICT (ledger_id, email) WHERE accepted_at IS NULL DO UPDATE
+	SET role = EXCLUDED.role,
+		invited_by = EXCLUDED.invited_by,
+		hash = EXCLUDED.hash,
+		expiry = EXCLUDED.expiry,
+		created_at = NOW()
+	RETURNING id, created_at
+	`
+
+	token, err := generateToken(invitation.InvitedBy, ttl)
+	if err != nil {
+		return err
+	}
+
+	invitation.Plaintext = token.Plaintext
+	invitation.Expiry = token.Expiry
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{
+		invitation.LedgerID,
+		invitation.Email,
+		invitation.Role,
+		invitation.InvitedBy,
+		token.Hash,
+		token.Expiry,
+	}
+
+	err = m.DB.QueryRowContext(ctx, query, args...).Scan(&invitation.ID, &invitation.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrAlreadyLedgerMember
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m LedgerModel) GetPendingInvitations(ledgerID int64) ([]*LedgerInvitation, error) {
+	query := `
+	SELECT id, created_at, ledger_id, email, role, invited_by, expiry
+	FROM ledger_invitations
+	WHERE ledger_id = $1 AND accepted_at IS NULL AND expiry > NOW()
+	ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	invitations := []*LedgerInvitation{}
+
+	for rows.Next() {
+		var invitation LedgerInvitation
+
+		err := rows.Scan(
+			&invitation.ID,
+			&invitation.CreatedAt,
+			&invitation.LedgerID,
+			&invitation.Email,
+			&invitation.Role,
+			&invitation.InvitedBy,
+			&invitation.Expiry,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		invitations = append(invitations, &invitation)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+func (m LedgerModel) DeleteInvitation(id int64, ledgerID int64) error {
+	query := `
+	DELETE FROM ledger_invitations
+	WHERE id = $1 AND ledger_id = $2 AND accepted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, ledgerID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m LedgerModel) AcceptInvitation(plaintext string, user *User) (int64, error) {
+	query := `
+	SELECT id, ledger_id, role
+	FROM ledger_invitations
+	WHERE hash = $1 AND email = $2 AND accepted_at IS NULL AND expiry > NOW()
+	FOR UPDATE
+	`
+
+	memberQuery := `
+	INSERT INTO ledger_members (ledger_id, user_id, role)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (ledger_id, user_id) DO NOTHING
+	`
+
+	acceptQuery := `
+	UPDATE ledger_invitations SET accepted_at = NOW()
+	WHERE id = $1
+	`
+
+	hash := sha256.Sum256([]byte(plaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var invitationID, ledgerID int64
+	var role LedgerRole
+
+	err = tx.QueryRowContext(ctx, query, hash[:], user.Email).Scan(&invitationID, &ledgerID, &role)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, memberQuery, ledgerID, user.ID, role)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, acceptQuery, invitationID)
+	if err != nil {
+		return 0, err
+	}
+
+	return ledgerID, tx.Commit()
+}
+
+func ValidateLedger(v *validator.Validator, ledger *Ledger) {
+	v.Check(ledger.Name != "", "name", "must be provided")
+	v.Check(len(ledger.Name) <= 200, "name", "must not be more than 200 bytes long")
+}
+
+func ValidateInvitedRole(v *validator.Validator, role LedgerRole) {
+	v.Check(role == LedgerEditor || role == LedgerViewer, "role", "must be editor or viewer")
+}
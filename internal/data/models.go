@@ -15,6 +15,19 @@ type Models struct {
 	Permissions  PermissionModel
 	Categories   CategoryModel
 	Transactions TransactionModel
+	Recurring    RecurringTransactionModel
+	Budgets      BudgetModel
+	Reports      ReportModel
+	Rates        ExchangeRateModel
+	Accounts     AccountModel
+	Transfers    TransferModel
+	Installments InstallmentPurchaseModel
+	Tokens       TokenModel
+	Ledgers      LedgerModel
+	Splits       SplitModel
+	Settlements  SettlementModel
+	Tags         TagModel
+	Rules        CategorizationRuleModel
 }
 
 func NewModels(db *sql.DB) Models {
@@ -23,5 +36,18 @@ func NewModels(db *sql.DB) Models {
 		Permissions:  PermissionModel{DB: db},
 		Categories:   CategoryModel{DB: db},
 		Transactions: TransactionModel{DB: db},
+		Recurring:    RecurringTransactionModel{DB: db},
+		Budgets:      BudgetModel{DB: db},
+		Reports:      ReportModel{DB: db},
+		Rates:        ExchangeRateModel{DB: db},
+		Accounts:     AccountModel{DB: db},
+		Transfers:    TransferModel{DB: db},
+		Installments: InstallmentPurchaseModel{DB: db},
+		Tokens:       TokenModel{DB: db},
+		Ledgers:      LedgerModel{DB: db},
+		Splits:       SplitModel{DB: db},
+		Settlements:  SettlementModel{DB: db},
+		Tags:         TagModel{DB: db},
+		Rules:        CategorizationRuleModel{DB: db},
 	}
 }
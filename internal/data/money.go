@@ -0,0 +1,138 @@
+package data
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const MaxMoney Money = 999_999_999_999_999
+
+var (
+	ErrInvalidMoneyFormat = errors.New("invalid amount format, expected a decimal number")
+	ErrMoneyPrecision     = errors.New("amount must not have more than two decimal places")
+)
+
+type Money int64
+
+func ParseMoney(s string) (Money, error) {
+	return parseMoney(s, false)
+}
+
+func parseMoney(s string, trailingZeros bool) (Money, error) {
+	s = strings.TrimSpace(s)
+
+	negative := false
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		negative = s[0] == '-'
+		s = s[1:]
+	}
+
+	units, cents, hasCents := strings.Cut(s, ".")
+	if units == "" || (hasCents && cents == "") || !isDigits(units) || !isDigits(cents) {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	if len(cents) > 2 {
+		if !trailingZeros || strings.TrimRight(cents[2:], "0") != "" {
+			return 0, ErrMoneyPrecision
+		}
+		cents = cents[:2]
+	}
+
+	cents += strings.Repeat("0", 2-len(cents))
+
+	u, err := strconv.ParseInt(units, 10, 64)
+	if err != nil || u > int64(MaxMoney)/100 {
+		return 0, ErrInvalidMoneyFormat
+	}
+
+	c, _ := strconv.ParseInt(cents, 10, 64)
+
+	m := Money(u*100 + c)
+	if negative {
+		m = -m
+	}
+
+	return m, nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+func (m Money) Percent(of Money) float64 {
+	if of == 0 {
+		return 0
+	}
+	return math.Round(float64(m)/float64(of)*10000) / 100
+}
+
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(jsonValue []byte) error {
+	s := string(jsonValue)
+
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+
+	*m = parsed
+	return nil
+}
+
+func (m *Money) Scan(value any) error {
+	switch v := value.(type) {
+	case []byte:
+		return m.Scan(string(v))
+	case string:
+		parsed, err := parseMoney(v, true)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
@@ -0,0 +1,158 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Money
+		err   error
+	}{
+		{"0", 0, nil},
+		{"10", 1000, nil},
+		{"10.5", 1050, nil},
+		{"10.05", 1005, nil},
+		{"+10.05", 1005, nil},
+		{"-10.05", -1005, nil},
+		{"-0.01", -1, nil},
+		{" 12.34 ", 1234, nil},
+		{"9999999999999.99", MaxMoney, nil},
+		{"-9999999999999.99", -MaxMoney, nil},
+		{"10000000000000", 0, ErrInvalidMoneyFormat},
+		{"99999999999999999999", 0, ErrInvalidMoneyFormat},
+		{"1.230", 0, ErrMoneyPrecision},
+		{"1.2300000", 0, ErrMoneyPrecision},
+		{"1.234", 0, ErrMoneyPrecision},
+		{".5", 0, ErrInvalidMoneyFormat},
+		{"1.", 0, ErrInvalidMoneyFormat},
+		{"", 0, ErrInvalidMoneyFormat},
+		{"-", 0, ErrInvalidMoneyFormat},
+		{"--1", 0, ErrInvalidMoneyFormat},
+		{"1,50", 0, ErrInvalidMoneyFormat},
+		{"1.5.0", 0, ErrInvalidMoneyFormat},
+		{"1e3", 0, ErrInvalidMoneyFormat},
+		{"abc", 0, ErrInvalidMoneyFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseMoney(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		input Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{1050, "10.50"},
+		{-1, "-0.01"},
+		{-1050, "-10.50"},
+		{MaxMoney, "9999999999999.99"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.input.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(tt.input), got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	for _, m := range []Money{0, 1, -1, 1050, -123456, MaxMoney, -MaxMoney} {
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("marshal %d: %v", int64(m), err)
+		}
+
+		var got Money
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Fatalf("unmarshal %s: %v", b, err)
+		}
+
+		if got != m {
+			t.Errorf("round trip of %d gave %d", int64(m), got)
+		}
+	}
+
+	tests := []struct {
+		input string
+		want  Money
+		err   bool
+	}{
+		{`12.5`, 1250, false},
+		{`"12.50"`, 1250, false},
+		{`"-0.10"`, -10, false},
+		{`12.345`, 0, true},
+		{`"abc"`, 0, true},
+		{`true`, 0, true},
+	}
+
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.input), &got)
+		if (err != nil) != tt.err {
+			t.Errorf("unmarshal %s: got error %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("unmarshal %s: got %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyScanValue(t *testing.T) {
+	for _, m := range []Money{0, 1, -1, 1050, MaxMoney, -MaxMoney} {
+		value, err := m.Value()
+		if err != nil {
+			t.Fatalf("value of %d: %v", int64(m), err)
+		}
+
+		var got Money
+		if err := got.Scan(value); err != nil {
+			t.Fatalf("scan %v: %v", value, err)
+		}
+
+		if got != m {
+			t.Errorf("round trip of %d gave %d", int64(m), got)
+		}
+	}
+
+	tests := []struct {
+		input any
+		want  Money
+		err   bool
+	}{
+		{[]byte("12.34"), 1234, false},
+		{"12.340", 1234, false},
+		{int64(12), 1200, false},
+		{"12.345", 0, true},
+		{3.5, 0, true},
+		{nil, 0, true},
+	}
+
+	for _, tt := range tests {
+		var got Money
+		err := got.Scan(tt.input)
+		if (err != nil) != tt.err {
+			t.Errorf("scan %v: got error %v, want error %v", tt.input, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("scan %v: got %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
@@ -14,15 +14,21 @@ type PermissionModel struct {
 	DB *sql.DB
 }
 
-func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
-	query := `
+const addPermissionsQuery = `
 	INSERT INTO users_permissions
-	SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)`
+	SELECT $1, permissions.id FROM permissions WHERE permissions.code = ANY($2)
+	ON CONFLICT DO NOTHING`
 
+func (m PermissionModel) AddForUser(userID int64, codes ...string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := m.DB.ExecContext(ctx, query, userID, pq.Array(codes))
+	_, err := m.DB.ExecContext(ctx, addPermissionsQuery, userID, pq.Array(codes))
+	return err
+}
+
+func addPermissionsForUser(ctx context.Context, tx *sql.Tx, userID int64, codes ...string) error {
+	_, err := tx.ExecContext(ctx, addPermissionsQuery, userID, pq.Array(codes))
 	return err
 }
 
@@ -67,3 +73,67 @@ func (m PermissionModel) GetAllForUser(userId int64) (Permissions, error) {
 
 	return permissions, nil
 }
+
+func (m PermissionModel) RemoveForUser(userID int64, code string) error {
+	query := `
+	DELETE FROM users_permissions
+	USING permissions
+	WHERE users_permissions.permission_id = permissions.id
+	AND users_permissions.user_id = $1
+	AND permissions.code = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, userID, code)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m PermissionModel) GetAll() (Permissions, error) {
+	query := `
+	SELECT code
+	FROM permissions
+	ORDER BY code ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	permissions := Permissions{}
+
+	for rows.Next() {
+		var permission string
+		err := rows.Scan(&permission)
+
+		if err != nil {
+			return nil, err
+		}
+
+		permissions = append(permissions, permission)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
@@ -0,0 +1,627 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+type Frequency int
+
+const maxOccurrencesPerRun = 366
+
+const (
+	DAILY Frequency = iota + 1
+	WEEKLY
+	MONTHLY
+	YEARLY
+)
+
+func (f Frequency) String() string {
+	switch f {
+	case DAILY:
+		return "DAILY"
+	case WEEKLY:
+		return "WEEKLY"
+	case MONTHLY:
+		return "MONTHLY"
+	case YEARLY:
+		return "YEARLY"
+	default:
+		return "Unknown"
+	}
+}
+
+func FrequencyFromString(s string) Frequency {
+	switch s {
+	case "DAILY":
+		return DAILY
+	case "WEEKLY":
+		return WEEKLY
+	case "MONTHLY":
+		return MONTHLY
+	case "YEARLY":
+		return YEARLY
+	default:
+		return 0
+	}
+}
+
+func (f Frequency) Occurrence(start Date, n int) Date {
+	switch f {
+	case DAILY:
+		return NewDate(start.AddDate(0, 0, n))
+	case WEEKLY:
+		return NewDate(start.AddDate(0, 0, 7*n))
+	case MONTHLY:
+		return addMonthsClamped(start, n)
+	case YEARLY:
+		return addMonthsClamped(start, 12*n)
+	default:
+		panic("unknown frequency: " + f.String())
+	}
+}
+
+func addMonthsClamped(start Date, months int) Date {
+	firstOfMonth := time.Date(start.Year(), start.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+
+	day := start.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+
+	return NewDate(firstOfMonth.AddDate(0, 0, day-1))
+}
+
+type RecurringTransaction struct {
+	ID                   int64
+	CreatedAt            time.Time
+	User                 *User
+	Category             *Category
+	Description          string
+	Amount               Money
+	Frequency            Frequency
+	StartDate            Date
+	EndDate              *Date
+	Occurrences          *int
+	OccurrencesGenerated int
+	NextRun              Date
+	Deleted              bool
+	Version              int
+}
+
+type RecurringTransactionDTO struct {
+	ID                   *int64       `json:"recurring_transaction_id"`
+	Version              *int         `json:"version"`
+	User                 *UserDTO     `json:"user"`
+	Category             *CategoryDTO `json:"category"`
+	Description          *string      `json:"description"`
+	Amount               *Money       `json:"amount"`
+	Frequency            *string      `json:"frequency"`
+	StartDate            *Date        `json:"start_date"`
+	EndDate              *Date        `json:"end_date"`
+	ClearEndDate         bool         `json:"clear_end_date,omitempty"`
+	Occurrences          *int         `json:"occurrences"`
+	OccurrencesGenerated *int         `json:"occurrences_generated"`
+	NextRun              *Date        `json:"next_run"`
+	CreatedAt            *time.Time   `json:"created_at"`
+}
+
+type RecurringTransactionModel struct {
+	DB *sql.DB
+}
+
+func (rt *RecurringTransaction) Finished() bool {
+	if rt.Occurrences != nil && rt.OccurrencesGenerated >= *rt.Occurrences {
+		return true
+	}
+
+	if rt.EndDate != nil && rt.NextRun.After(rt.EndDate.Time) {
+		return true
+	}
+
+	return false
+}
+
+func (rt *RecurringTransaction) ToDTO() *RecurringTransactionDTO {
+	dto := &RecurringTransactionDTO{}
+
+	if rt.ID != 0 {
+		dto.ID = &rt.ID
+	}
+
+	if rt.Version != 0 {
+		dto.Version = &rt.Version
+	}
+
+	if rt.User != nil {
+		dto.User = rt.User.ToDTO()
+	}
+
+	if rt.Category != nil {
+		dto.Category = rt.Category.ToDTO()
+	}
+
+	if rt.Description != "" {
+		dto.Description = &rt.Description
+	}
+
+	if rt.Amount != 0 {
+		dto.Amount = &rt.Amount
+	}
+
+	if rt.Frequency != 0 {
+		frequency := rt.Frequency.String()
+		dto.Frequency = &frequency
+	}
+
+	dto.StartDate = &rt.StartDate
+	dto.EndDate = rt.EndDate
+	dto.Occurrences = rt.Occurrences
+	dto.OccurrencesGenerated = &rt.OccurrencesGenerated
+
+	if !rt.Finished() {
+		dto.NextRun = &rt.NextRun
+	}
+
+	dto.CreatedAt = &rt.CreatedAt
+
+	return dto
+}
+
+func (dto *RecurringTransactionDTO) ToModel() *RecurringTransaction {
+	rt := &RecurringTransaction{}
+
+	if dto.ID != nil {
+		rt.ID = *dto.ID
+	}
+	if dto.Version != nil {
+		rt.Version = *dto.Version
+	}
+	if dto.User != nil {
+		rt.User = dto.User.ToModel()
+	}
+	if dto.Category != nil {
+		rt.Category = dto.Category.ToModel()
+	}
+	if dto.Description != nil {
+		rt.Description = *dto.Description
+	}
+	if dto.Amount != nil {
+		rt.Amount = *dto.Amount
+	}
+	if dto.Frequency != nil {
+		rt.Frequency = FrequencyFromString(*dto.Frequency)
+	}
+	if dto.StartDate != nil {
+		rt.StartDate = *dto.StartDate
+	}
+
+	rt.EndDate = dto.EndDate
+	rt.Occurrences = dto.Occurrences
+	rt.NextRun = rt.StartDate
+
+	return rt
+}
+
+func (dto *RecurringTransactionDTO) ToDTOUpdateRecurringTransaction(rt *RecurringTransaction) {
+	if dto.Version != nil {
+		rt.Version = *dto.Version
+	}
+
+	if dto.Category != nil {
+		rt.Category = dto.Category.ToModel()
+	}
+
+	if dto.Description != nil {
+		rt.Description = *dto.Description
+	}
+
+	if dto.Amount != nil {
+		rt.Amount = *dto.Amount
+	}
+
+	if dto.Frequency != nil {
+		rt.Frequency = FrequencyFromString(*dto.Frequency)
+	}
+
+	if dto.StartDate != nil {
+		rt.StartDate = *dto.StartDate
+	}
+
+	if dto.ClearEndDate {
+		rt.EndDate = nil
+	} else if dto.EndDate != nil {
+		rt.EndDate = dto.EndDate
+	}
+
+	if dto.Occurrences != nil {
+		rt.Occurrences = dto.Occurrences
+	}
+
+	if rt.OccurrencesGenerated == 0 {
+		rt.NextRun = rt.StartDate
+	}
+}
+
+func (m RecurringTransactionModel) Insert(rt *RecurringTransaction) error {
+	query := `
+	INSERT INTO recurring_transactions (
+		user_id,
+		category_id,
+		description,
+		amount,
+		frequency,
+		start_date,
+		end_date,
+		occurrences,
+		next_run
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id, created_at, occurrences_generated, version
+	`
+
+	args := []any{
+		rt.User.ID,
+		rt.Category.ID,
+		rt.Description,
+		rt.Amount,
+		rt.Frequency,
+		rt.StartDate,
+		rt.EndDate,
+		rt.Occurrences,
+		rt.NextRun,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, args...).Scan(
+		&rt.ID,
+		&rt.CreatedAt,
+		&rt.OccurrencesGenerated,
+		&rt.Version,
+	)
+}
+
+func (m RecurringTransactionModel) GetByID(id int64, userID int64) (*RecurringTransaction, error) {
+	query := `
+	SELECT id, created_at, user_id, category_id, description, amount, frequency,
+		start_date, end_date, occurrences, occurrences_generated, next_run, deleted, version
+	FROM recurring_transactions
+	WHERE id = $1 AND user_id = $2 AND deleted = false
+	`
+
+	rt := RecurringTransaction{
+		User:     &User{},
+		Category: &Category{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&rt.ID,
+		&rt.CreatedAt,
+		&rt.User.ID,
+		&rt.Category.ID,
+		&rt.Description,
+		&rt.Amount,
+		&rt.Frequency,
+		&rt.StartDate,
+		&rt.EndDate,
+		&rt.Occurrences,
+		&rt.OccurrencesGenerated,
+		&rt.NextRun,
+		&rt.Deleted,
+		&rt.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rt, nil
+}
+
+func (m RecurringTransactionModel) GetAll(description string, userID int64, filters Filters) ([]*RecurringTransaction, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, user_id, category_id, description, amount, frequency,
+		start_date, end_date, occurrences, occurrences_generated, next_run, deleted, version
+	FROM recurring_transactions
+	WHERE (to_tsvector('simple', description) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND user_id = $2 AND deleted = false
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, description, userID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	recurring := []*RecurringTransaction{}
+
+	for rows.Next() {
+		rt := RecurringTransaction{
+			User:     &User{},
+			Category: &Category{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&rt.ID,
+			&rt.CreatedAt,
+			&rt.User.ID,
+			&rt.Category.ID,
+			&rt.Description,
+			&rt.Amount,
+			&rt.Frequency,
+			&rt.StartDate,
+			&rt.EndDate,
+			&rt.Occurrences,
+			&rt.OccurrencesGenerated,
+			&rt.NextRun,
+			&rt.Deleted,
+			&rt.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		recurring = append(recurring, &rt)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return recurring, metaData, nil
+}
+
+func (m RecurringTransactionModel) Update(rt *RecurringTransaction, userID int64) error {
+	query := `
+	UPDATE recurring_transactions
+	SET category_id = $1,
+		description = $2,
+		amount = $3,
+		frequency = $4,
+		start_date = $5,
+		end_date = $6,
+		occurrences = $7,
+		next_run = $8,
+		finished = $9,
+		version = version + 1
+	WHERE
+		id = $10
+		AND user_id = $11
+		AND deleted = false
+		AND version = $12
+	RETURNING version
+	`
+
+	args := []any{
+		rt.Category.ID,
+		rt.Description,
+		rt.Amount,
+		rt.Frequency,
+		rt.StartDate,
+		rt.EndDate,
+		rt.Occurrences,
+		rt.NextRun,
+		rt.Finished(),
+		rt.ID,
+		userID,
+		rt.Version,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(&rt.Version)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m RecurringTransactionModel) Delete(id int64, userID int64) error {
+	query := `
+	UPDATE recurring_transactions
+	SET
+		deleted = true
+	WHERE
+		id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m RecurringTransactionModel) DueIDs(today Date) ([]int64, error) {
+	query := `
+	SELECT id
+	FROM recurring_transactions
+	WHERE deleted = false AND finished = false AND next_run <= $1
+	ORDER BY id
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, today)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func (m RecurringTransactionModel) Materialize(id int64, today Date) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := `
+	SELECT user_id, category_id, description, amount, frequency,
+		start_date, end_date, occurrences, occurrences_generated, next_run
+	FROM recurring_transactions
+	WHERE id = $1 AND deleted = false AND finished = false
+	FOR UPDATE SKIP LOCKED
+	`
+
+	rt := RecurringTransaction{
+		ID:       id,
+		User:     &User{},
+		Category: &Category{},
+	}
+
+	err = tx.QueryRowContext(ctx, query, id).Scan(
+		&rt.User.ID,
+		&rt.Category.ID,
+		&rt.Description,
+		&rt.Amount,
+		&rt.Frequency,
+		&rt.StartDate,
+		&rt.EndDate,
+		&rt.Occurrences,
+		&rt.OccurrencesGenerated,
+		&rt.NextRun,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, nil
+		default:
+			return 0, err
+		}
+	}
+
+	insert := `
+	INSERT INTO transactions (user_id, category_id, description, amount, recurring_transaction_id, recurring_date, occurred_on, currency)
+	VALUES ($1, $2, $3, $4, $5, $6, $6, (SELECT currency FROM users WHERE id = $1))
+	ON CONFLICT (recurring_transaction_id, recurring_date) WHERE recurring_transaction_id IS NOT NULL DO NOTHING
+	`
+
+	created := 0
+	for n := 0; n < maxOccurrencesPerRun && !rt.Finished() && !rt.NextRun.After(today.Time); n++ {
+		result, err := tx.ExecContext(ctx, insert,
+			rt.User.ID,
+			rt.Category.ID,
+			rt.Description,
+			rt.Amount,
+			rt.ID,
+			rt.NextRun,
+		)
+		if err != nil {
+			return 0, err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		created += int(rowsAffected)
+
+		rt.OccurrencesGenerated++
+		rt.NextRun = rt.Frequency.Occurrence(rt.StartDate, rt.OccurrencesGenerated)
+	}
+
+	update := `
+	UPDATE recurring_transactions
+	SET occurrences_generated = $1,
+		next_run = $2,
+		finished = $3
+	WHERE id = $4
+	`
+
+	_, err = tx.ExecContext(ctx, update, rt.OccurrencesGenerated, rt.NextRun, rt.Finished(), rt.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return created, nil
+}
+
+func ValidateRecurringTransaction(v *validator.Validator, rt *RecurringTransaction) {
+	v.Check(rt.User != nil, "user", "must be provided")
+	v.Check(rt.Category != nil, "category", "must be provided")
+	v.Check(rt.Description != "", "description", "must be provided")
+	v.Check(len(rt.Description) <= 500, "description", "must not be more than 500 bytes long")
+	v.Check(rt.Amount > 0, "amount", "must be positive")
+	v.Check(rt.Amount <= MaxMoney, "amount", "must not be more than 9999999999999.99")
+	v.Check(rt.Frequency != 0, "frequency", "must be one of DAILY, WEEKLY, MONTHLY or YEARLY")
+	v.Check(!rt.StartDate.IsZero(), "start_date", "must be provided")
+
+	if rt.EndDate != nil {
+		v.Check(!rt.EndDate.Before(rt.StartDate.Time), "end_date", "must not be before start_date")
+	}
+
+	if rt.Occurrences != nil {
+		v.Check(*rt.Occurrences > 0, "occurrences", "must be greater than zero")
+	}
+}
@@ -0,0 +1,354 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+var (
+	TimeSeriesGranularities = []string{"day", "week", "month", "year"}
+	TimeSeriesGroupings     = []string{"none", "category", "category_type"}
+)
+
+const maxTimeSeriesBuckets = 1000
+
+const convertedTransactions = `
+	converted AS (
+		SELECT t.id, t.user_id, t.category_id, t.occurred_on, t.currency, t.amount AS original_amount,
+			CASE
+				WHEN t.currency = u.currency THEN t.amount
+				ELSE ROUND(t.amount * COALESCE(
+					(SELECT er.rate FROM exchange_rates er
+					WHERE er.base_currency = t.currency AND er.quote_currency = u.currency AND er.rate_date <= t.occurred_on
+					ORDER BY er.rate_date DESC LIMIT 1),
+					(SELECT 1 / er.rate FROM exchange_rates er
+					WHERE er.base_currency = u.currency AND er.quote_currency = t.currency AND er.rate_date <= t.occurred_on
+					ORDER BY er.rate_date DESC LIMIT 1)
+				), 2)
+			END AS amount
+		FROM transactions t
+		INNER JOIN categories tc ON tc.id = t.category_id
+		INNER JOIN users u ON u.id = $1
+		WHERE tc.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $1)
+		AND t.deleted = false AND t.transfer_id IS NULL
+	)`
+
+const categoryTree = `
+	category_tree AS (
+		SELECT c.id, c.id AS ancestor_id, c.parent_id, 0 AS depth
+		FROM categories c
+		WHERE c.id IN (SELECT category_id FROM converted)
+		UNION ALL
+		SELECT ct.id, p.id, p.parent_id, ct.depth + 1
+		FROM category_tree ct
+		INNER JOIN categories p ON p.id = ct.parent_id
+		WHERE ct.depth < 10
+	)`
+
+type ReportModel struct {
+	DB *sql.DB
+}
+
+type Summary struct {
+	StartDate         *time.Time         `json:"start_date,omitempty"`
+	EndDate           *time.Time         `json:"end_date,omitempty"`
+	Currency          string             `json:"currency"`
+	Unconverted       int                `json:"unconverted_transactions"`
+	TotalIncome       Money              `json:"total_income"`
+	TotalExpense      Money              `json:"total_expense"`
+	Balance           Money              `json:"balance"`
+	Categories        []*CategorySummary `json:"categories"`
+	UnconvertedTotals []*CurrencyTotal   `json:"unconverted"`
+}
+
+type CurrencyTotal struct {
+	Currency     string `json:"currency"`
+	TotalIncome  Money  `json:"total_income"`
+	TotalExpense Money  `json:"total_expense"`
+	Count        int    `json:"count"`
+}
+
+type TimeSeries struct {
+	Key    int64              `json:"key,omitempty"`
+	Label  string             `json:"label,omitempty"`
+	Points []*TimeSeriesPoint `json:"points"`
+}
+
+type TimeSeriesPoint struct {
+	Period string `json:"period"`
+	Total  Money  `json:"total"`
+	Count  int    `json:"count"`
+}
+
+type CategorySummary struct {
+	CategoryID int64  `json:"category_id"`
+	ParentID   *int64 `json:"parent_id"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Color      string `json:"color"`
+	Total      Money  `json:"total"`
+	OwnTotal   Money  `json:"own_total"`
+	Count      int    `json:"count"`
+}
+
+func (m ReportModel) Summary(userID int64, startDate, endDate *time.Time) (*Summary, error) {
+	query := `
+	WITH RECURSIVE ` + convertedTransactions + `, ` + categoryTree + `
+	SELECT c.id, c.parent_id, c.name, c.type, c.color,
+		COALESCE(SUM(t.amount), 0),
+		COALESCE(SUM(t.amount) FILTER (WHERE ct.depth = 0), 0),
+		COUNT(t.id)
+	FROM converted t
+	INNER JOIN category_tree ct ON ct.id = t.category_id
+	INNER JOIN categories c ON c.id = ct.ancestor_id
+	WHERE ($2::date IS NULL OR t.occurred_on >= $2::date)
+	AND ($3::date IS NULL OR t.occurred_on <= $3::date)
+	GROUP BY c.id, c.parent_id, c.name, c.type, c.color
+	ORDER BY c.type ASC, SUM(t.amount) DESC, c.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	start := nullDate(startDate)
+	end := nullDate(endDate)
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	summary := &Summary{
+		StartDate:  startDate,
+		EndDate:    endDate,
+		Categories: []*CategorySummary{},
+	}
+
+	for rows.Next() {
+		var categoryType TypeCategoria
+		var parentID sql.NullInt64
+		category := CategorySummary{}
+
+		err := rows.Scan(
+			&category.CategoryID,
+			&parentID,
+			&category.Name,
+			&categoryType,
+			&category.Color,
+			&category.Total,
+			&category.OwnTotal,
+			&category.Count,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if parentID.Valid {
+			category.ParentID = &parentID.Int64
+		}
+		category.Type = categoryType.String()
+		summary.Categories = append(summary.Categories, &category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	totals := `
+	WITH ` + convertedTransactions + `
+	SELECT
+		COALESCE(SUM(t.amount) FILTER (WHERE c.type = $4), 0),
+		COALESCE(SUM(t.amount) FILTER (WHERE c.type = $5), 0),
+		COALESCE(SUM(CASE WHEN c.type = $4 THEN t.amount ELSE -t.amount END), 0),
+		COUNT(t.id) FILTER (WHERE t.amount IS NULL),
+		(SELECT currency FROM users WHERE id = $1)
+	FROM converted t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE ($2::date IS NULL OR t.occurred_on >= $2::date)
+	AND ($3::date IS NULL OR t.occurred_on <= $3::date)
+	`
+
+	err = m.DB.QueryRowContext(ctx, totals, userID, start, end, RECEITA, DESPESA).Scan(
+		&summary.TotalIncome,
+		&summary.TotalExpense,
+		&summary.Balance,
+		&summary.Unconverted,
+		&summary.Currency,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	unconverted := `
+	WITH ` + convertedTransactions + `
+	SELECT t.currency,
+		COALESCE(SUM(t.original_amount) FILTER (WHERE c.type = $4), 0),
+		COALESCE(SUM(t.original_amount) FILTER (WHERE c.type = $5), 0),
+		COUNT(t.id)
+	FROM converted t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE t.amount IS NULL
+	AND ($2::date IS NULL OR t.occurred_on >= $2::date)
+	AND ($3::date IS NULL OR t.occurred_on <= $3::date)
+	GROUP BY t.currency
+	ORDER BY t.currency ASC
+	`
+
+	summary.UnconvertedTotals = []*CurrencyTotal{}
+	if summary.Unconverted == 0 {
+		return summary, nil
+	}
+
+	rows, err = m.DB.QueryContext(ctx, unconverted, userID, start, end, RECEITA, DESPESA)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		total := CurrencyTotal{}
+
+		err := rows.Scan(&total.Currency, &total.TotalIncome, &total.TotalExpense, &total.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.UnconvertedTotals = append(summary.UnconvertedTotals, &total)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+func (m ReportModel) TimeSeries(userID int64, start, end time.Time, granularity, groupBy string, categoryType TypeCategoria) ([]*TimeSeries, error) {
+	groupKey, groupLabel := timeSeriesGroupColumns(groupBy)
+
+	groups := "SELECT DISTINCT group_key, group_label FROM totals"
+	if groupBy == "none" {
+		groups = "SELECT 0::bigint AS group_key, ''::text AS group_label"
+	}
+
+	query := fmt.Sprintf(`
+	WITH RECURSIVE `+convertedTransactions+`, `+categoryTree+`,
+	buckets AS (
+		SELECT generate_series(
+			date_trunc($2, $3::timestamp),
+			date_trunc($2, $4::timestamp),
+			('1 ' || $2)::interval
+		) AS bucket
+	),
+	totals AS (
+		SELECT date_trunc($2, t.occurred_on::timestamp) AS bucket,
+			%s AS group_key,
+			%s AS group_label,
+			SUM(t.amount) AS total,
+			COUNT(t.id) AS count
+		FROM converted t
+		INNER JOIN category_tree ct ON ct.id = t.category_id AND ct.parent_id IS NULL
+		INNER JOIN categories c ON c.id = ct.ancestor_id
+		WHERE t.occurred_on >= date_trunc($2, $3::timestamp)::date
+		AND t.occurred_on <= $4::date
+		AND ($5 = 0 OR c.type = $5)
+		GROUP BY 1, 2, 3
+	),
+	groups AS (
+		%s
+	)
+	SELECT b.bucket, g.group_key, g.group_label, COALESCE(tt.total, 0), COALESCE(tt.count, 0)
+	FROM buckets b
+	CROSS JOIN groups g
+	LEFT JOIN totals tt ON tt.bucket = b.bucket AND tt.group_key = g.group_key
+	ORDER BY g.group_key ASC, b.bucket ASC
+	`, groupKey, groupLabel, groups)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, granularity, NewDate(start), NewDate(end), categoryType)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	series := []*TimeSeries{}
+	var current *TimeSeries
+
+	for rows.Next() {
+		var bucket time.Time
+		var key int64
+		var label string
+		point := TimeSeriesPoint{}
+
+		err := rows.Scan(&bucket, &key, &label, &point.Total, &point.Count)
+		if err != nil {
+			return nil, err
+		}
+
+		if current == nil || current.Key != key {
+			current = &TimeSeries{Key: key, Label: label, Points: []*TimeSeriesPoint{}}
+			if groupBy == "category_type" {
+				current.Label = TypeCategoria(key).String()
+			}
+			series = append(series, current)
+		}
+
+		point.Period = bucket.Format("2006-01-02")
+		current.Points = append(current.Points, &point)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return series, nil
+}
+
+func timeSeriesGroupColumns(groupBy string) (string, string) {
+	switch groupBy {
+	case "none":
+		return "0::bigint", "''::text"
+	case "category":
+		return "c.id", "c.name::text"
+	case "category_type":
+		return "c.type::bigint", "''::text"
+	}
+	panic("unsafe group_by parameter: " + groupBy)
+}
+
+func timeSeriesBucketCount(granularity string, start, end time.Time) int {
+	switch granularity {
+	case "day":
+		return int(end.Sub(start).Hours()/24) + 1
+	case "week":
+		return int(end.Sub(start).Hours()/(24*7)) + 1
+	case "month":
+		return (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+	case "year":
+		return end.Year() - start.Year() + 1
+	default:
+		return 0
+	}
+}
+
+func ValidateTimeSeries(v *validator.Validator, granularity, groupBy string, start, end *time.Time) {
+	v.Check(validator.In(granularity, TimeSeriesGranularities...), "granularity", "must be one of day, week, month or year")
+	v.Check(validator.In(groupBy, TimeSeriesGroupings...), "group_by", "must be one of none, category or category_type")
+	v.Check(start != nil, "start", "must be provided in the format YYYY-MM-DD")
+	v.Check(end != nil, "end", "must be provided in the format YYYY-MM-DD")
+
+	if start != nil && end != nil {
+		v.Check(!end.Before(*start), "end", "must not be before start")
+		v.Check(timeSeriesBucketCount(granularity, *start, *end) <= maxTimeSeriesBuckets, "granularity",
+			fmt.Sprintf("must not produce more than %d buckets for the given range", maxTimeSeriesBuckets))
+	}
+}
@@ -0,0 +1,268 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+var (
+	ErrSettlementExceedsBalance = errors.New("settlement exceeds the outstanding balance")
+)
+
+type Settlement struct {
+	ID         int64
+	CreatedAt  time.Time
+	LedgerID   int64
+	From       *User
+	To         *User
+	Amount     Money
+	Currency   string
+	OccurredOn Date
+	CreatedBy  int64
+	Version    int
+}
+
+type SettlementDTO struct {
+	ID         int64     `json:"settlement_id"`
+	Version    int       `json:"version"`
+	LedgerID   int64     `json:"ledger_id"`
+	From       *UserDTO  `json:"from"`
+	To         *UserDTO  `json:"to"`
+	Amount     Money     `json:"amount"`
+	Currency   string    `json:"currency"`
+	OccurredOn Date      `json:"occurred_on"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type SettlementModel struct {
+	DB *sql.DB
+}
+
+func (s *Settlement) ToDTO() *SettlementDTO {
+	return &SettlementDTO{
+		ID:         s.ID,
+		Version:    s.Version,
+		LedgerID:   s.LedgerID,
+		From:       s.From.ToDTO(),
+		To:         s.To.ToDTO(),
+		Amount:     s.Amount,
+		Currency:   s.Currency,
+		OccurredOn: s.OccurredOn,
+		CreatedAt:  s.CreatedAt,
+	}
+}
+
+func (m SettlementModel) Insert(settlement *Settlement) error {
+	lockQuery := `
+	SELECT id FROM ledgers WHERE id = $1 FOR UPDATE
+	`
+
+	query := `
+	INSERT INTO settlements (ledger_id, from_user_id, to_user_id, amount, currency, occurred_on, created_by)
+	VALUES ($1, $2, $3, $4, $5, COALESCE($6::date, CURRENT_DATE), $7)
+	RETURNING id, created_at, occurred_on, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var ledgerID int64
+	err = tx.QueryRowContext(ctx, lockQuery, settlement.LedgerID).Scan(&ledgerID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, balancesQuery, settlement.LedgerID)
+	if err != nil {
+		return err
+	}
+
+	balances, err := scanBalances(rows)
+	if err != nil {
+		return err
+	}
+
+	balance := OutstandingBalance(balances, settlement.From.ID, settlement.To.ID, settlement.Currency)
+	if balance == nil || settlement.Amount > balance.Amount {
+		return ErrSettlementExceedsBalance
+	}
+
+	args := []any{
+		settlement.LedgerID,
+		settlement.From.ID,
+		settlement.To.ID,
+		settlement.Amount,
+		settlement.Currency,
+		settlement.OccurredOn.nullable(),
+		settlement.CreatedBy,
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&settlement.ID,
+		&settlement.CreatedAt,
+		&settlement.OccurredOn,
+		&settlement.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m SettlementModel) GetByID(id int64, ledgerID int64) (*Settlement, error) {
+	query := `
+	SELECT id, created_at, ledger_id, from_user_id, to_user_id, amount, currency, occurred_on, created_by, version
+	FROM settlements
+	WHERE id = $1 AND ledger_id = $2 AND deleted = false
+	`
+
+	settlement := Settlement{
+		From: &User{},
+		To:   &User{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, ledgerID).Scan(
+		&settlement.ID,
+		&settlement.CreatedAt,
+		&settlement.LedgerID,
+		&settlement.From.ID,
+		&settlement.To.ID,
+		&settlement.Amount,
+		&settlement.Currency,
+		&settlement.OccurredOn,
+		&settlement.CreatedBy,
+		&settlement.Version,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &settlement, nil
+}
+
+func (m SettlementModel) GetAll(ledgerID int64, filters Filters) ([]*Settlement, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), s.id, s.created_at, s.ledger_id, s.amount, s.currency, s.occurred_on, s.created_by, s.version,
+		f.id, f.name, f.email, f.phone, o.id, o.name, o.email, o.phone
+	FROM settlements s
+	INNER JOIN users f ON f.id = s.from_user_id
+	INNER JOIN users o ON o.id = s.to_user_id
+	WHERE s.ledger_id = $1 AND s.deleted = false
+	ORDER BY s.%s %s, s.id ASC
+	LIMIT $2 OFFSET $3
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, ledgerID, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	settlements := []*Settlement{}
+
+	for rows.Next() {
+		settlement := Settlement{
+			From: &User{},
+			To:   &User{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&settlement.ID,
+			&settlement.CreatedAt,
+			&settlement.LedgerID,
+			&settlement.Amount,
+			&settlement.Currency,
+			&settlement.OccurredOn,
+			&settlement.CreatedBy,
+			&settlement.Version,
+			&settlement.From.ID,
+			&settlement.From.Name,
+			&settlement.From.Email,
+			&settlement.From.Phone,
+			&settlement.To.ID,
+			&settlement.To.Name,
+			&settlement.To.Email,
+			&settlement.To.Phone,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		settlements = append(settlements, &settlement)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return settlements, metaData, nil
+}
+
+func (m SettlementModel) Delete(id int64, ledgerID int64) error {
+	query := `
+	UPDATE settlements
+	SET deleted = true, version = version + 1
+	WHERE id = $1 AND ledger_id = $2 AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	result, err := m.DB.ExecContext(ctx, query, id, ledgerID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func ValidateSettlement(v *validator.Validator, settlement *Settlement) {
+	v.Check(settlement.From != nil && settlement.From.ID > 0, "from_user_id", "must be provided")
+	v.Check(settlement.To != nil && settlement.To.ID > 0, "to_user_id", "must be provided")
+	if settlement.From != nil && settlement.To != nil {
+		v.Check(settlement.From.ID != settlement.To.ID, "to_user_id", "must be different from the payer")
+	}
+	v.Check(settlement.Amount > 0, "amount", "must be positive")
+	v.Check(settlement.Amount <= MaxMoney, "amount", "must not be more than 9999999999999.99")
+	ValidateCurrency(v, "currency", settlement.Currency)
+}
@@ -0,0 +1,284 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"meus_gastos/internal/validator"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Split struct {
+	User   *User
+	Amount Money
+}
+
+type SplitDTO struct {
+	User   *UserDTO `json:"user"`
+	Amount Money    `json:"amount"`
+}
+
+type Balance struct {
+	From     *UserDTO `json:"from"`
+	To       *UserDTO `json:"to"`
+	Currency string   `json:"currency"`
+	Amount   Money    `json:"amount"`
+}
+
+type SplitModel struct {
+	DB *sql.DB
+}
+
+func (s *Split) ToDTO() *SplitDTO {
+	return &SplitDTO{
+		User:   s.User.ToDTO(),
+		Amount: s.Amount,
+	}
+}
+
+func SplitEqually(total Money, n int) []Money {
+	shares := make([]Money, n)
+	if n == 0 {
+		return shares
+	}
+
+	share := total / Money(n)
+	for i := range shares {
+		shares[i] = share
+	}
+	shares[0] += total - share*Money(n)
+
+	return shares
+}
+
+func (m SplitModel) GetForTransaction(transactionID int64) ([]*Split, error) {
+	query := `
+	SELECT u.id, u.name, u.email, u.phone, s.amount
+	FROM transaction_splits s
+	INNER JOIN users u ON u.id = s.user_id
+	WHERE s.transaction_id = $1
+	ORDER BY s.amount DESC, u.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	splits := []*Split{}
+
+	for rows.Next() {
+		split := Split{
+			User: &User{},
+		}
+
+		err := rows.Scan(
+			&split.User.ID,
+			&split.User.Name,
+			&split.User.Email,
+			&split.User.Phone,
+			&split.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		splits = append(splits, &split)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return splits, nil
+}
+
+func (m SplitModel) Replace(transactionID int64, splits []*Split) error {
+	deleteQuery := `
+	DELETE FROM transaction_splits
+	WHERE transaction_id = $1
+	`
+
+	insertQuery := `
+	INSERT INTO transaction_splits (transaction_id, user_id, amount)
+	VALUES ($1, $2, $3)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, deleteQuery, transactionID)
+	if err != nil {
+		return err
+	}
+
+	for _, split := range splits {
+		_, err = tx.ExecContext(ctx, insertQuery, transactionID, split.User.ID, split.Amount)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+const balancesQuery = `
+	WITH debts AS (
+		SELECT s.user_id AS debtor, t.user_id AS creditor, t.currency, s.amount
+		FROM transaction_splits s
+		INNER JOIN transactions t ON t.id = s.transaction_id
+		INNER JOIN categories c ON c.id = t.category_id
+		WHERE c.ledger_id = $1 AND t.deleted = false AND s.user_id <> t.user_id
+		UNION ALL
+		SELECT to_user_id, from_user_id, currency, amount
+		FROM settlements
+		WHERE ledger_id = $1 AND deleted = false
+	), net AS (
+		SELECT LEAST(debtor, creditor) AS a,
+			GREATEST(debtor, creditor) AS b,
+			currency,
+			SUM(CASE WHEN debtor < creditor THEN amount ELSE -amount END) AS amount
+		FROM debts
+		GROUP BY 1, 2, 3
+	)
+	SELECT f.id, f.name, f.email, f.phone, o.id, o.name, o.email, o.phone, n.currency, ABS(n.amount)
+	FROM net n
+	INNER JOIN users f ON f.id = CASE WHEN n.amount > 0 THEN n.a ELSE n.b END
+	INNER JOIN users o ON o.id = CASE WHEN n.amount > 0 THEN n.b ELSE n.a END
+	WHERE n.amount <> 0
+	ORDER BY n.currency ASC, ABS(n.amount) DESC
+	`
+
+func (m SplitModel) GetBalances(ledgerID int64) ([]*Balance, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, balancesQuery, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanBalances(rows)
+}
+
+func scanBalances(rows *sql.Rows) ([]*Balance, error) {
+	defer rows.Close()
+
+	balances := []*Balance{}
+
+	for rows.Next() {
+		balance := Balance{
+			From: &UserDTO{},
+			To:   &UserDTO{},
+		}
+
+		err := rows.Scan(
+			&balance.From.ID,
+			&balance.From.Name,
+			&balance.From.Email,
+			&balance.From.Phone,
+			&balance.To.ID,
+			&balance.To.Name,
+			&balance.To.Email,
+			&balance.To.Phone,
+			&balance.Currency,
+			&balance.Amount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, &balance)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
+
+func OutstandingBalance(balances []*Balance, fromID, toID int64, currency string) *Balance {
+	for _, b := range balances {
+		if b.From.ID == fromID && b.To.ID == toID && b.Currency == currency {
+			return b
+		}
+	}
+
+	return nil
+}
+
+func (m SplitModel) GetSplitTransactionIDs(ids []int64) (map[int64]bool, error) {
+	query := `
+	SELECT DISTINCT transaction_id
+	FROM transaction_splits
+	WHERE transaction_id = ANY($1)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	split := map[int64]bool{}
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		split[id] = true
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return split, nil
+}
+
+func SplitsTotal(splits []*Split) Money {
+	var total Money
+	for _, split := range splits {
+		total += split.Amount
+	}
+
+	return total
+}
+
+func CanMoveSplitTransaction(from, to *Category) bool {
+	return to.Type == DESPESA && to.Ledger.ID == from.Ledger.ID
+}
+
+func ValidateSplits(v *validator.Validator, total Money, splits []*Split) {
+	v.Check(len(splits) > 0, "splits", "must contain at least one share")
+
+	seen := make(map[int64]bool, len(splits))
+	for _, split := range splits {
+		v.Check(split.User != nil && split.User.ID > 0, "splits", "must reference valid users")
+		if split.User != nil {
+			v.Check(!seen[split.User.ID], "splits", "must not contain duplicate users")
+			seen[split.User.ID] = true
+		}
+		v.Check(split.Amount > 0, "splits", "share amounts must be positive")
+	}
+
+	v.Check(SplitsTotal(splits) == total, "splits", "share amounts must add up to the transaction amount")
+}
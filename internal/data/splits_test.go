@@ -0,0 +1,25 @@
+package data
+
+import "testing"
+
+func TestCanMoveSplitTransaction(t *testing.T) {
+	from := &Category{Type: DESPESA, Ledger: &Ledger{ID: 1}}
+
+	tests := []struct {
+		name string
+		to   *Category
+		want bool
+	}{
+		{"expense in the same ledger", &Category{Type: DESPESA, Ledger: &Ledger{ID: 1}}, true},
+		{"income in the same ledger", &Category{Type: RECEITA, Ledger: &Ledger{ID: 1}}, false},
+		{"expense in another ledger", &Category{Type: DESPESA, Ledger: &Ledger{ID: 2}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanMoveSplitTransaction(from, tt.to); got != tt.want {
+				t.Errorf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
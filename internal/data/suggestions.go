@@ -0,0 +1,199 @@
+package data
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	SuggestionSourceRule    = "rule"
+	SuggestionSourceHistory = "history"
+
+	maxSuggestionSamples = 1000
+)
+
+type CategorySuggestion struct {
+	Category     *CategoryDTO `json:"category"`
+	Confidence   float64      `json:"confidence"`
+	Source       string       `json:"source"`
+	Transactions int          `json:"transactions"`
+	LastUsed     *Date        `json:"last_used,omitempty"`
+	RuleID       int64        `json:"rule_id,omitempty"`
+}
+
+type suggestionSample struct {
+	category   *Category
+	tokens     []string
+	occurredOn Date
+}
+
+func (m TransactionModel) SuggestCategories(description string, userID int64, ledgerID int64, categoryType TypeCategoria, limit int) ([]*CategorySuggestion, error) {
+	tokensQuery := `
+	SELECT tsvector_to_array(to_tsvector('simple', $1))
+	`
+
+	query := `
+	WITH query_tokens AS (
+		SELECT unnest($2::text[]) AS token
+	)
+	SELECT c.id, c.name, c.type, c.color, c.ledger_id, t.occurred_on,
+		ARRAY(
+			SELECT token FROM query_tokens
+			INTERSECT
+			SELECT unnest(tsvector_to_array(to_tsvector('simple', t.description)))
+		)
+	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE to_tsvector('simple', t.description) @@ to_tsquery('simple', (
+		SELECT string_agg(quote_literal(token), ' | ') FROM query_tokens
+	))
+	AND c.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $1)
+	AND ($3 = 0 OR c.ledger_id = $3)
+	AND ($4 = 0 OR c.type = $4)
+	AND t.deleted = false AND t.transfer_id IS NULL AND c.deleted = false
+	ORDER BY t.occurred_on DESC, t.id DESC
+	LIMIT $5
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var tokens []string
+	err := m.DB.QueryRowContext(ctx, tokensQuery, description).Scan(pq.Array(&tokens))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(tokens) == 0 {
+		return []*CategorySuggestion{}, nil
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, pq.Array(tokens), ledgerID, categoryType, maxSuggestionSamples)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	samples := []suggestionSample{}
+
+	for rows.Next() {
+		sample := suggestionSample{
+			category: &Category{Ledger: &Ledger{}},
+		}
+
+		err := rows.Scan(
+			&sample.category.ID,
+			&sample.category.Name,
+			&sample.category.Type,
+			&sample.category.Color,
+			&sample.category.Ledger.ID,
+			&sample.occurredOn,
+			pq.Array(&sample.tokens),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		samples = append(samples, sample)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rankCategorySuggestions(tokens, samples, time.Now(), limit), nil
+}
+
+func rankCategorySuggestions(tokens []string, samples []suggestionSample, now time.Time, limit int) []*CategorySuggestion {
+	df := make(map[string]int, len(tokens))
+	for _, s := range samples {
+		for _, token := range s.tokens {
+			df[token]++
+		}
+	}
+
+	n := float64(len(samples))
+	idf := make(map[string]float64, len(tokens))
+	var totalWeight float64
+	for _, token := range tokens {
+		idf[token] = math.Log(1 + (n+1)/float64(df[token]+1))
+		totalWeight += idf[token]
+	}
+
+	type candidate struct {
+		suggestion   *CategorySuggestion
+		score        float64
+		bestCoverage float64
+		lastUsed     Date
+	}
+
+	candidates := map[int64]*candidate{}
+	var totalScore float64
+
+	for _, s := range samples {
+		var matched float64
+		for _, token := range s.tokens {
+			matched += idf[token]
+		}
+
+		coverage := 0.0
+		if totalWeight > 0 {
+			coverage = matched / totalWeight
+		}
+
+		age := now.Sub(s.occurredOn.Time).Hours() / 24
+		recency := 1 / (1 + math.Max(age, 0)/365)
+		score := coverage * coverage * recency
+
+		c, ok := candidates[s.category.ID]
+		if !ok {
+			c = &candidate{
+				suggestion: &CategorySuggestion{
+					Category: s.category.ToDTO(),
+					Source:   SuggestionSourceHistory,
+				},
+			}
+			candidates[s.category.ID] = c
+		}
+
+		c.score += score
+		c.bestCoverage = math.Max(c.bestCoverage, coverage)
+		c.suggestion.Transactions++
+		if s.occurredOn.After(c.lastUsed.Time) {
+			c.lastUsed = s.occurredOn
+		}
+
+		totalScore += score
+	}
+
+	suggestions := []*CategorySuggestion{}
+	for _, c := range candidates {
+		if totalScore > 0 {
+			c.suggestion.Confidence = math.Round(c.score/totalScore*c.bestCoverage*100) / 100
+		}
+		lastUsed := c.lastUsed
+		c.suggestion.LastUsed = &lastUsed
+		suggestions = append(suggestions, c.suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Confidence != suggestions[j].Confidence {
+			return suggestions[i].Confidence > suggestions[j].Confidence
+		}
+		if suggestions[i].Transactions != suggestions[j].Transactions {
+			return suggestions[i].Transactions > suggestions[j].Transactions
+		}
+		return *suggestions[i].Category.ID < *suggestions[j].Category.ID
+	})
+
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions
+}
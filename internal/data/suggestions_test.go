@@ -0,0 +1,136 @@
+package data
+
+import (
+	"testing"
+	"time"
+)
+
+func suggestionCategory(id int64) *Category {
+	return &Category{ID: id, Name: "category", Type: DESPESA, Color: "#000000", Ledger: &Ledger{ID: 1}}
+}
+
+func TestRankCategorySuggestions(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	samples := []suggestionSample{
+		{suggestionCategory(1), []string{"uber", "trip"}, date("2024-06-01")},
+		{suggestionCategory(1), []string{"uber"}, date("2023-06-02")},
+		{suggestionCategory(2), []string{"trip"}, date("2024-05-30")},
+		{suggestionCategory(3), []string{"trip"}, date("2024-05-30")},
+		{suggestionCategory(3), []string{"trip"}, date("2024-05-30")},
+	}
+
+	want := []struct {
+		categoryID   int64
+		confidence   float64
+		transactions int
+		lastUsed     string
+	}{
+		{1, 0.69, 2, "2024-06-01"},
+		{3, 0.09, 2, "2024-05-30"},
+		{2, 0.04, 1, "2024-05-30"},
+	}
+
+	got := rankCategorySuggestions([]string{"uber", "trip"}, samples, now, 10)
+	if len(got) != len(want) {
+		t.Fatalf("got %d suggestions, want %d", len(got), len(want))
+	}
+
+	var total float64
+	for i, w := range want {
+		s := got[i]
+
+		if *s.Category.ID != w.categoryID {
+			t.Errorf("position %d: got category %d, want %d", i, *s.Category.ID, w.categoryID)
+		}
+		if s.Confidence != w.confidence {
+			t.Errorf("position %d: got confidence %v, want %v", i, s.Confidence, w.confidence)
+		}
+		if s.Transactions != w.transactions {
+			t.Errorf("position %d: got %d transactions, want %d", i, s.Transactions, w.transactions)
+		}
+		if s.LastUsed == nil || s.LastUsed.String() != w.lastUsed {
+			t.Errorf("position %d: got last used %v, want %s", i, s.LastUsed, w.lastUsed)
+		}
+		if s.Source != SuggestionSourceHistory {
+			t.Errorf("position %d: got source %q, want %q", i, s.Source, SuggestionSourceHistory)
+		}
+		if s.Confidence < 0 || s.Confidence > 1 {
+			t.Errorf("position %d: confidence %v is out of [0, 1]", i, s.Confidence)
+		}
+
+		total += s.Confidence
+	}
+
+	if total > 1.005 {
+		t.Errorf("confidences add up to %v, want at most 1", total)
+	}
+
+	limited := rankCategorySuggestions([]string{"uber", "trip"}, samples, now, 1)
+	if len(limited) != 1 || *limited[0].Category.ID != 1 {
+		t.Errorf("got %d suggestions with limit 1, want only category 1", len(limited))
+	}
+}
+
+func TestRankCategorySuggestionsFullMatch(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	samples := []suggestionSample{
+		{suggestionCategory(7), []string{"netflix"}, date("2024-06-01")},
+	}
+
+	got := rankCategorySuggestions([]string{"netflix"}, samples, now, 3)
+	if len(got) != 1 || got[0].Confidence != 1 {
+		t.Fatalf("got %+v, want a single suggestion with confidence 1", got)
+	}
+}
+
+func TestRankCategorySuggestionsTieBreak(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		samples []suggestionSample
+		want    []int64
+	}{
+		{
+			name: "same confidence orders by category id",
+			samples: []suggestionSample{
+				{suggestionCategory(5), []string{"mercado"}, date("2024-06-01")},
+				{suggestionCategory(4), []string{"mercado"}, date("2024-06-01")},
+			},
+			want: []int64{4, 5},
+		},
+		{
+			name: "same confidence orders by transactions before id",
+			samples: []suggestionSample{
+				{suggestionCategory(1), []string{"mercado"}, date("2024-06-01")},
+				{suggestionCategory(2), []string{"mercado"}, date("2023-06-02")},
+				{suggestionCategory(2), []string{"mercado"}, date("2023-06-02")},
+			},
+			want: []int64{2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rankCategorySuggestions([]string{"mercado"}, tt.samples, now, 10)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d suggestions, want %d", len(got), len(tt.want))
+			}
+
+			for i, id := range tt.want {
+				if *got[i].Category.ID != id {
+					t.Errorf("position %d: got category %d (confidence %v), want %d", i, *got[i].Category.ID, got[i].Confidence, id)
+				}
+			}
+		})
+	}
+}
+
+func TestRankCategorySuggestionsEmpty(t *testing.T) {
+	got := rankCategorySuggestions([]string{"nada"}, nil, time.Now(), 3)
+	if got == nil || len(got) != 0 {
+		t.Errorf("got %v, want an empty slice", got)
+	}
+}
@@ -0,0 +1,147 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"meus_gastos/internal/validator"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	MaxTagsPerTransaction = 20
+
+	TagsMatchAny = "any"
+	TagsMatchAll = "all"
+)
+
+var TagRX = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}_-]{0,49}$`)
+
+const transactionTags = `ARRAY(
+		SELECT tg.name FROM transaction_tags tt
+		INNER JOIN tags tg ON tg.id = tt.tag_id
+		WHERE tt.transaction_id = t.id
+		ORDER BY tg.name
+	)`
+
+type Tag struct {
+	Name         string `json:"name"`
+	LedgerID     int64  `json:"ledger_id"`
+	Transactions int    `json:"transactions"`
+}
+
+type TagModel struct {
+	DB *sql.DB
+}
+
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+
+	return normalized
+}
+
+func setTransactionTags(ctx context.Context, tx *sql.Tx, transactionID int64, tags []string) error {
+	upsertQuery := `
+	INSERT INTO tags (ledger_id, name)
+	SELECT c.ledger_id, unnest($2::text[])
+	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE t.id = $1
+	ON CONFLICT (ledger_id, name) DO NOTHING
+	`
+
+	deleteQuery := `
+	DELETE FROM transaction_tags
+	WHERE transaction_id = $1
+	`
+
+	linkQuery := `
+	INSERT INTO transaction_tags (transaction_id, tag_id)
+	SELECT t.id, tg.id
+	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
+	INNER JOIN tags tg ON tg.ledger_id = c.ledger_id
+	WHERE t.id = $1 AND tg.name = ANY($2)
+	`
+
+	_, err := tx.ExecContext(ctx, upsertQuery, transactionID, pq.Array(tags))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, deleteQuery, transactionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, linkQuery, transactionID, pq.Array(tags))
+	return err
+}
+
+func (m TagModel) GetAll(userID int64, ledgerID int64) ([]*Tag, error) {
+	query := `
+	SELECT tg.name, tg.ledger_id, COUNT(t.id)
+	FROM tags tg
+	INNER JOIN ledger_members lm ON lm.ledger_id = tg.ledger_id AND lm.user_id = $1
+	LEFT JOIN transaction_tags tt ON tt.tag_id = tg.id
+	LEFT JOIN transactions t ON t.id = tt.transaction_id AND t.deleted = false
+	WHERE ($2 = 0 OR tg.ledger_id = $2)
+	GROUP BY tg.id, tg.name, tg.ledger_id
+	HAVING COUNT(t.id) > 0
+	ORDER BY COUNT(t.id) DESC, tg.name ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, ledgerID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	tags := []*Tag{}
+
+	for rows.Next() {
+		var tag Tag
+
+		err := rows.Scan(&tag.Name, &tag.LedgerID, &tag.Transactions)
+		if err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+func ValidateTags(v *validator.Validator, key string, tags []string) {
+	v.Check(len(tags) <= MaxTagsPerTransaction, key, "must not contain more than 20 tags")
+
+	for _, tag := range tags {
+		if !validator.Matches(tag, TagRX) {
+			v.AddError(key, "must contain only letters, digits, hyphens or underscores (up to 50 characters)")
+			return
+		}
+	}
+}
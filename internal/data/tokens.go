@@ -0,0 +1,198 @@
+package data
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+var (
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+type Token struct {
+	Plaintext string    `json:"token"`
+	Hash      []byte    `json:"-"`
+	UserID    int64     `json:"-"`
+	Expiry    time.Time `json:"expiry"`
+}
+
+type TokenModel struct {
+	DB *sql.DB
+}
+
+func generateToken(userID int64, ttl time.Duration) (*Token, error) {
+	token := &Token{
+		UserID: userID,
+		Expiry: time.Now().Add(ttl),
+	}
+
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
+
+	hash := sha256.Sum256([]byte(token.Plaintext))
+	token.Hash = hash[:]
+
+	return token, nil
+}
+
+func (m TokenModel) NewRefreshToken(userID int64, ttl time.Duration) (*Token, error) {
+	token, err := generateToken(userID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.Insert(token)
+	return token, err
+}
+
+func (m TokenModel) Insert(token *Token) error {
+	query := `
+	INSERT INTO refresh_tokens (hash, user_id, expiry)
+	VALUES ($1, $2, $3)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, token.Hash, token.UserID, token.Expiry)
+	return err
+}
+
+func (m TokenModel) Rotate(tokenPlaintext string) (int64, error) {
+	lookup := `
+	SELECT user_id, revoked
+	FROM refresh_tokens
+	WHERE hash = $1 AND expiry > NOW()
+	FOR UPDATE
+	`
+
+	revoke := `
+	UPDATE refresh_tokens
+	SET revoked = true
+	WHERE hash = $1
+	`
+
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	var revoked bool
+
+	err = tx.QueryRowContext(ctx, lookup, hash[:]).Scan(&userID, &revoked)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	if revoked {
+		return userID, ErrTokenRevoked
+	}
+
+	_, err = tx.ExecContext(ctx, revoke, hash[:])
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, tx.Commit()
+}
+
+func (m TokenModel) RevokeRefreshToken(tokenPlaintext string, userID int64) error {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked = true
+	WHERE hash = $1 AND user_id = $2
+	`
+
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, hash[:], userID)
+	return err
+}
+
+func (m TokenModel) RevokeAllForUser(userID int64) error {
+	query := `
+	UPDATE refresh_tokens
+	SET revoked = true
+	WHERE user_id = $1 AND revoked = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (m TokenModel) RevokeAccessToken(jti string, expiry time.Time) error {
+	query := `
+	INSERT INTO revoked_access_tokens (jti, expiry)
+	VALUES ($1, $2)
+	ON CONFLICT (jti) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query, jti, expiry)
+	return err
+}
+
+func (m TokenModel) IsAccessTokenRevoked(jti string) (bool, error) {
+	query := `
+	SELECT EXISTS (SELECT 1 FROM revoked_access_tokens WHERE jti = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var revoked bool
+	err := m.DB.QueryRowContext(ctx, query, jti).Scan(&revoked)
+	return revoked, err
+}
+
+func (m TokenModel) DeleteExpired() error {
+	query := `
+	WITH expired_refresh AS (
+		DELETE FROM refresh_tokens WHERE expiry < NOW()
+	)
+	DELETE FROM revoked_access_tokens WHERE expiry < NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := m.DB.ExecContext(ctx, query)
+	return err
+}
+
+func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
+	v.Check(tokenPlaintext != "", "token", "must be provided")
+	v.Check(len(tokenPlaintext) == 26, "token", "must be 26 bytes long")
+}
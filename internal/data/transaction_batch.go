@@ -0,0 +1,109 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	OpCreate = "create"
+	OpUpdate = "update"
+	OpDelete = "delete"
+
+	MaxBatchOperations = 500
+)
+
+const (
+	BatchStatusOK       = "ok"
+	BatchStatusInvalid  = "invalid"
+	BatchStatusNotFound = "not_found"
+	BatchStatusConflict = "conflict"
+	BatchStatusSkipped  = "skipped"
+	BatchStatusReverted = "rolled_back"
+)
+
+type TransactionOperation struct {
+	Op          string
+	Transaction *Transaction
+}
+
+type TransactionOperationResult struct {
+	Index         int               `json:"index"`
+	Op            string            `json:"op"`
+	Status        string            `json:"status"`
+	TransactionID int64             `json:"transaction_id,omitempty"`
+	Version       int               `json:"version,omitempty"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+func (m TransactionModel) ExecBatch(operations []*TransactionOperation, results []*TransactionOperationResult, userID int64) error {
+	deleteQuery := `
+	UPDATE transactions
+	SET deleted = true, version = version + 1
+	WHERE
+		id = $1
+		AND category_id IN (
+			SELECT c.id FROM categories c
+			INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id
+			WHERE lm.user_id = $2 AND lm.role <> 'viewer'
+		)
+		AND deleted = false
+		AND transfer_id IS NULL
+		AND version = $3
+	RETURNING version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for i, op := range operations {
+		transaction := op.Transaction
+
+		switch op.Op {
+		case OpCreate:
+			err = insertTransaction(ctx, tx, transaction)
+		case OpUpdate:
+			err = updateTransaction(ctx, tx, transaction, userID)
+		case OpDelete:
+			err = tx.QueryRowContext(ctx, deleteQuery, transaction.ID, userID, transaction.Version).Scan(&transaction.Version)
+			if errors.Is(err, sql.ErrNoRows) {
+				err = ErrEditConflict
+			}
+		}
+
+		if err != nil {
+			for _, result := range results[:i] {
+				result.Status = BatchStatusReverted
+				result.Version = 0
+				if result.Op == OpCreate {
+					result.TransactionID = 0
+				}
+			}
+
+			for _, result := range results[i+1:] {
+				result.Status = BatchStatusSkipped
+			}
+
+			if errors.Is(err, ErrEditConflict) {
+				results[i].Status = BatchStatusConflict
+				results[i].Errors = map[string]string{"version": "the record was modified or deleted, reload it and try again"}
+			}
+
+			return err
+		}
+
+		results[i].Status = BatchStatusOK
+		results[i].TransactionID = transaction.ID
+		results[i].Version = transaction.Version
+	}
+
+	return tx.Commit()
+}
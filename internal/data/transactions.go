@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"meus_gastos/internal/validator"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type TransactionModel struct {
@@ -14,14 +16,20 @@ type TransactionModel struct {
 }
 
 type Transaction struct {
-	ID          int64
-	CreatedAt   time.Time
-	Deleted     bool
-	Version     int
-	User        *User
-	Category    *Category
-	Description string
-	Amount      float64
+	ID              int64
+	CreatedAt       time.Time
+	OccurredOn      Date
+	Deleted         bool
+	Version         int
+	User            *User
+	Category        *Category
+	Account         *Account
+	Description     string
+	Amount          Money
+	Currency        string
+	Tags            []string
+	FITID           string
+	ExternalAccount string
 }
 
 type TransactionDTO struct {
@@ -29,8 +37,12 @@ type TransactionDTO struct {
 	Version     *int         `json:"version"`
 	User        *UserDTO     `json:"user"`
 	Category    *CategoryDTO `json:"category"`
+	Account     *AccountDTO  `json:"account,omitempty"`
 	Description *string      `json:"description"`
-	Amount      *float64     `json:"amount"`
+	Amount      *Money       `json:"amount"`
+	Currency    *string      `json:"currency"`
+	OccurredOn  *Date        `json:"occurred_on"`
+	Tags        []string     `json:"tags"`
 	CreatedAt   *time.Time   `json:"created_at"`
 }
 
@@ -53,6 +65,10 @@ func (t *Transaction) ToDTO() *TransactionDTO {
 		dto.Category = t.Category.ToDTO()
 	}
 
+	if t.Account != nil {
+		dto.Account = t.Account.ToDTO()
+	}
+
 	if t.Description != "" {
 		dto.Description = &t.Description
 	}
@@ -61,6 +77,19 @@ func (t *Transaction) ToDTO() *TransactionDTO {
 		dto.Amount = &t.Amount
 	}
 
+	if t.Currency != "" {
+		dto.Currency = &t.Currency
+	}
+
+	if !t.OccurredOn.IsZero() {
+		dto.OccurredOn = &t.OccurredOn
+	}
+
+	dto.Tags = t.Tags
+	if dto.Tags == nil {
+		dto.Tags = []string{}
+	}
+
 	dto.CreatedAt = &t.CreatedAt
 
 	return dto
@@ -81,12 +110,22 @@ func (t *TransactionDTO) ToModel() *Transaction {
 	if t.Category != nil {
 		transaction.Category = t.Category.ToModel()
 	}
+	if t.Account != nil {
+		transaction.Account = t.Account.ToModel()
+	}
 	if t.Description != nil {
 		transaction.Description = *t.Description
 	}
 	if t.Amount != nil {
 		transaction.Amount = *t.Amount
 	}
+	if t.Currency != nil {
+		transaction.Currency = *t.Currency
+	}
+	if t.OccurredOn != nil {
+		transaction.OccurredOn = *t.OccurredOn
+	}
+	transaction.Tags = NormalizeTags(t.Tags)
 
 	return transaction
 }
@@ -108,6 +147,10 @@ func (t *TransactionDTO) ToDTOUpdateTransaction(transaction *Transaction) {
 		transaction.Category = t.Category.ToModel()
 	}
 
+	if t.Account != nil {
+		transaction.Account = t.Account.ToModel()
+	}
+
 	if t.Description != nil {
 		transaction.Description = *t.Description
 	}
@@ -115,6 +158,18 @@ func (t *TransactionDTO) ToDTOUpdateTransaction(transaction *Transaction) {
 	if t.Amount != nil {
 		transaction.Amount = *t.Amount
 	}
+
+	if t.Currency != nil {
+		transaction.Currency = *t.Currency
+	}
+
+	if t.OccurredOn != nil {
+		transaction.OccurredOn = *t.OccurredOn
+	}
+
+	if t.Tags != nil {
+		transaction.Tags = NormalizeTags(t.Tags)
+	}
 }
 
 func (m TransactionModel) GetAllByUserAndCategory(description string, userID int64, categoryID int64, startDate, endDate *time.Time, filters Filters) ([]*Transaction, Metadata, error) {
@@ -127,36 +182,40 @@ func (m TransactionModel) GetAllByUserAndCategory(description string, userID int
 	t.user_id, 
 	t.category_id, 
 	t.description, 
-	t.amount
+	t.amount,
+	t.currency,
+	t.occurred_on,
+	t.account_id,
+	`+transactionTags+`
 	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
 	WHERE (to_tsvector('simple', t.description) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND t.user_id = $2 AND t.deleted = false AND t.category_id = $3
-	AND ($4::timestamptz IS NULL OR t.created_at >= $4::timestamptz)
-	AND ($5::timestamptz IS NULL OR t.created_at <= $5::timestamptz)
-	ORDER BY %s %s, id ASC
+	AND c.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $2)
+	AND t.deleted = false AND t.category_id IN (
+		WITH RECURSIVE subcategories AS (
+			SELECT $3::bigint AS id, 0 AS depth
+			UNION ALL
+			SELECT c.id, s.depth + 1 FROM categories c
+			INNER JOIN subcategories s ON c.parent_id = s.id
+			WHERE c.deleted = false AND s.depth < 10
+		)
+		SELECT id FROM subcategories
+	)
+	AND ($4::date IS NULL OR t.occurred_on >= $4::date)
+	AND ($5::date IS NULL OR t.occurred_on <= $5::date)
+	ORDER BY t.%s %s, t.id ASC
 	LIMIT $6 OFFSET $7
 	`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	start := sql.NullTime{}
-	if startDate != nil {
-		start.Valid = true
-		start.Time = *startDate
-	}
-
-	end := sql.NullTime{}
-	if endDate != nil {
-		end.Valid = true
-		end.Time = *endDate
-	}
 	args := []any{
 		description,
 		userID,
 		categoryID,
-		start,
-		end,
+		nullDate(startDate),
+		nullDate(endDate),
 		filters.limit(),
 		filters.offset(),
 	}
@@ -176,6 +235,7 @@ func (m TransactionModel) GetAllByUserAndCategory(description string, userID int
 			User:     &User{},
 			Category: &Category{User: &User{}},
 		}
+		var accountID sql.NullInt64
 		err := rows.Scan(
 			&totalRecords,
 			&transaction.ID,
@@ -186,10 +246,15 @@ func (m TransactionModel) GetAllByUserAndCategory(description string, userID int
 			&transaction.Category.ID,
 			&transaction.Description,
 			&transaction.Amount,
+			&transaction.Currency,
+			&transaction.OccurredOn,
+			&accountID,
+			pq.Array(&transaction.Tags),
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		transaction.Account = accountFromNullID(accountID)
 		transactions = append(transactions, &transaction)
 	}
 
@@ -197,7 +262,7 @@ func (m TransactionModel) GetAllByUserAndCategory(description string, userID int
 	return transactions, metaData, nil
 }
 
-func (m TransactionModel) GetAllByUser(description string, userID int64, startDate, endDate *time.Time, categoryType TypeCategoria, filters Filters) ([]*Transaction, Metadata, error) {
+func (m TransactionModel) GetAllByUser(description string, userID int64, startDate, endDate *time.Time, categoryType TypeCategoria, accountID int64, ledgerID int64, tags []string, tagsMatch string, filters Filters) ([]*Transaction, Metadata, error) {
 	query := fmt.Sprintf(`
 	SELECT count(*) OVER(), 
 		t.id, 
@@ -207,40 +272,50 @@ func (m TransactionModel) GetAllByUser(description string, userID int64, startDa
 		t.user_id, 
 		t.category_id, 
 		t.description, 
-		t.amount
+		t.amount,
+		t.currency,
+		t.occurred_on,
+		t.account_id,
+		`+transactionTags+`
 	FROM transactions t
 	INNER JOIN categories c ON c.id = t.category_id
 	WHERE (to_tsvector('simple', t.description) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND t.user_id = $2 
+	AND c.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $2)
 	AND t.deleted = false
-	AND ($3::timestamptz IS NULL OR t.created_at >= $3::timestamptz)
-	AND ($4::timestamptz IS NULL OR t.created_at <= $4::timestamptz)
-	AND ($5 IS NULL OR c.type = $5)
-	ORDER BY %s %s, t.id ASC
-	LIMIT $6 OFFSET $7
+	AND ($3::date IS NULL OR t.occurred_on >= $3::date)
+	AND ($4::date IS NULL OR t.occurred_on <= $4::date)
+	AND ($5 = 0 OR c.type = $5)
+	AND ($6 = 0 OR t.account_id = $6)
+	AND ($7 = 0 OR c.ledger_id = $7)
+	AND (cardinality($8::text[]) = 0 OR CASE WHEN $9 = 'all'
+		THEN (
+			SELECT COUNT(*) FROM transaction_tags tt
+			INNER JOIN tags tg ON tg.id = tt.tag_id
+			WHERE tt.transaction_id = t.id AND tg.name = ANY($8)
+		) = cardinality($8::text[])
+		ELSE EXISTS (
+			SELECT 1 FROM transaction_tags tt
+			INNER JOIN tags tg ON tg.id = tt.tag_id
+			WHERE tt.transaction_id = t.id AND tg.name = ANY($8)
+		)
+	END)
+	ORDER BY t.%s %s, t.id ASC
+	LIMIT $10 OFFSET $11
 	`, filters.sortColumn(), filters.sortDirection())
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	start := sql.NullTime{}
-	if startDate != nil {
-		start.Valid = true
-		start.Time = *startDate
-	}
-
-	end := sql.NullTime{}
-	if endDate != nil {
-		end.Valid = true
-		end.Time = *endDate
-	}
-
 	args := []any{
 		description,
 		userID,
-		start,
-		end,
+		nullDate(startDate),
+		nullDate(endDate),
 		categoryType,
+		accountID,
+		ledgerID,
+		pq.Array(tags),
+		tagsMatch,
 		filters.limit(),
 		filters.offset(),
 	}
@@ -261,6 +336,7 @@ func (m TransactionModel) GetAllByUser(description string, userID int64, startDa
 			User:     &User{},
 			Category: &Category{User: &User{}},
 		}
+		var accountRef sql.NullInt64
 		err := rows.Scan(
 			&totalRecords,
 			&transaction.ID,
@@ -271,10 +347,15 @@ func (m TransactionModel) GetAllByUser(description string, userID int64, startDa
 			&transaction.Category.ID,
 			&transaction.Description,
 			&transaction.Amount,
+			&transaction.Currency,
+			&transaction.OccurredOn,
+			&accountRef,
+			pq.Array(&transaction.Tags),
 		)
 		if err != nil {
 			return nil, Metadata{}, err
 		}
+		transaction.Account = accountFromNullID(accountRef)
 		transactions = append(transactions, &transaction)
 	}
 
@@ -282,14 +363,147 @@ func (m TransactionModel) GetAllByUser(description string, userID int64, startDa
 	return transactions, metaData, nil
 }
 
+func (m TransactionModel) Export(description string, userID int64, startDate, endDate *time.Time, categoryType TypeCategoria, fn func(*Transaction) error) error {
+	query := `
+	SELECT t.id,
+		t.created_at,
+		t.version,
+		t.user_id,
+		t.description,
+		t.amount,
+		t.currency,
+		t.occurred_on,
+		c.id,
+		c.name,
+		c.type,
+		c.color,
+		` + transactionTags + `
+	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE (to_tsvector('simple', t.description) @@ plainto_tsquery('simple', $1) OR $1 = '')
+	AND c.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $2)
+	AND t.deleted = false
+	AND ($3::date IS NULL OR t.occurred_on >= $3::date)
+	AND ($4::date IS NULL OR t.occurred_on <= $4::date)
+	AND ($5 = 0 OR c.type = $5)
+	ORDER BY t.occurred_on ASC, t.id ASC
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, description, userID, nullDate(startDate), nullDate(endDate), categoryType)
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		transaction := Transaction{
+			User:     &User{},
+			Category: &Category{},
+		}
+
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.CreatedAt,
+			&transaction.Version,
+			&transaction.User.ID,
+			&transaction.Description,
+			&transaction.Amount,
+			&transaction.Currency,
+			&transaction.OccurredOn,
+			&transaction.Category.ID,
+			&transaction.Category.Name,
+			&transaction.Category.Type,
+			&transaction.Category.Color,
+			pq.Array(&transaction.Tags),
+		)
+		if err != nil {
+			return err
+		}
+
+		err = fn(&transaction)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func (m TransactionModel) GetForCategorization(userID int64, startDate, endDate *time.Time, limit int) ([]*Transaction, error) {
+	query := `
+	SELECT t.id, t.description, t.amount, t.occurred_on, t.category_id, c.type, c.ledger_id, t.account_id
+	FROM transactions t
+	INNER JOIN categories c ON c.id = t.category_id
+	WHERE t.user_id = $1 AND t.deleted = false AND t.transfer_id IS NULL
+	AND c.ledger_id IN (SELECT ledger_id FROM ledger_members WHERE user_id = $1 AND role <> 'viewer')
+	AND ($2::date IS NULL OR t.occurred_on >= $2::date)
+	AND ($3::date IS NULL OR t.occurred_on <= $3::date)
+	ORDER BY t.occurred_on ASC, t.id ASC
+	LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, userID, nullDate(startDate), nullDate(endDate), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	transactions := []*Transaction{}
+
+	for rows.Next() {
+		transaction := Transaction{
+			Category: &Category{Ledger: &Ledger{}},
+		}
+		var accountID sql.NullInt64
+
+		err := rows.Scan(
+			&transaction.ID,
+			&transaction.Description,
+			&transaction.Amount,
+			&transaction.OccurredOn,
+			&transaction.Category.ID,
+			&transaction.Category.Type,
+			&transaction.Category.Ledger.ID,
+			&accountID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		transaction.Account = accountFromNullID(accountID)
+		transactions = append(transactions, &transaction)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
 func (m TransactionModel) GetByID(id int64, userID int64) (*Transaction, error) {
 	query := `
-	SELECT id, created_at, deleted, version, user_id, category_id, description, amount
-	FROM transactions
-	WHERE id = $1 AND user_id = $2 AND deleted = false
+	SELECT t.id, t.created_at, t.deleted, t.version, t.user_id, t.category_id, t.description, t.amount, t.currency, t.occurred_on, t.account_id,
+	` + transactionTags + `
+	FROM transactions t
+	WHERE t.id = $1 AND t.deleted = false AND t.transfer_id IS NULL
+	AND t.category_id IN (
+		SELECT c.id FROM categories c
+		INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id
+		WHERE lm.user_id = $2
+	)
 	`
 
 	var tx Transaction
+	var accountID sql.NullInt64
 	tx.User = &User{}
 	tx.Category = &Category{}
 
@@ -305,6 +519,10 @@ func (m TransactionModel) GetByID(id int64, userID int64) (*Transaction, error)
 		&tx.Category.ID,
 		&tx.Description,
 		&tx.Amount,
+		&tx.Currency,
+		&tx.OccurredOn,
+		&accountID,
+		pq.Array(&tx.Tags),
 	)
 
 	if err != nil {
@@ -316,19 +534,42 @@ func (m TransactionModel) GetByID(id int64, userID int64) (*Transaction, error)
 		}
 	}
 
+	tx.Account = accountFromNullID(accountID)
+
 	return &tx, nil
 }
 
 func (m TransactionModel) Insert(transaction *Transaction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertTransaction(ctx, tx, transaction)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertTransaction(ctx context.Context, tx *sql.Tx, transaction *Transaction) error {
 	query := `
 	INSERT INTO transactions ( 
 			user_id, 
 			category_id, 
 			description, 
 			amount, 
+			occurred_on,
+			currency,
+			account_id
 	)
-	VALUES ($1, $2, $3, $4)
-	RETURNING id,created_at, version
+	VALUES ($1, $2, $3, $4, COALESCE($5::date, CURRENT_DATE), COALESCE(NULLIF($6, ''), (SELECT currency FROM users WHERE id = $1)), $7)
+	RETURNING id, created_at, version, occurred_on, currency
 	`
 
 	args := []any{
@@ -336,53 +577,189 @@ func (m TransactionModel) Insert(transaction *Transaction) error {
 		transaction.Category.ID,
 		transaction.Description,
 		transaction.Amount,
+		transaction.OccurredOn.nullable(),
+		transaction.Currency,
+		nullAccountID(transaction.Account),
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&transaction.ID,
 		&transaction.CreatedAt,
 		&transaction.Version,
+		&transaction.OccurredOn,
+		&transaction.Currency,
 	)
 
 	if err != nil {
 		return err
 	}
+
+	if transaction.Tags != nil {
+		return setTransactionTags(ctx, tx, transaction.ID, transaction.Tags)
+	}
+
 	return nil
 }
 
+func (m TransactionModel) InsertBatch(transactions []*Transaction) error {
+	query := `
+	INSERT INTO transactions (user_id, category_id, description, amount, occurred_on, currency, account_id)
+	VALUES ($1, $2, $3, $4, COALESCE($5::date, CURRENT_DATE), COALESCE(NULLIF($6, ''), (SELECT currency FROM users WHERE id = $1)), $7)
+	RETURNING id, created_at, version, occurred_on, currency
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, transaction := range transactions {
+		err = stmt.QueryRowContext(ctx,
+			transaction.User.ID,
+			transaction.Category.ID,
+			transaction.Description,
+			transaction.Amount,
+			transaction.OccurredOn.nullable(),
+			transaction.Currency,
+			nullAccountID(transaction.Account),
+		).Scan(
+			&transaction.ID,
+			&transaction.CreatedAt,
+			&transaction.Version,
+			&transaction.OccurredOn,
+			&transaction.Currency,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m TransactionModel) InsertImported(transactions []*Transaction) (int, error) {
+	query := `
+	INSERT INTO transactions (user_id, category_id, description, amount, occurred_on, external_account, fitid, currency, account_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), (SELECT currency FROM users WHERE id = $1)), $9)
+	ON CONFLICT (user_id, external_account, fitid) WHERE fitid IS NOT NULL DO NOTHING
+	RETURNING id, created_at, version
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, transaction := range transactions {
+		err = stmt.QueryRowContext(ctx,
+			transaction.User.ID,
+			transaction.Category.ID,
+			transaction.Description,
+			transaction.Amount,
+			transaction.OccurredOn,
+			transaction.ExternalAccount,
+			transaction.FITID,
+			transaction.Currency,
+			nullAccountID(transaction.Account),
+		).Scan(
+			&transaction.ID,
+			&transaction.CreatedAt,
+			&transaction.Version,
+		)
+
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			continue
+		case err != nil:
+			return 0, err
+		}
+
+		inserted++
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return 0, err
+	}
+
+	return inserted, nil
+}
+
 func (m TransactionModel) Update(transaction *Transaction, userID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = updateTransaction(ctx, tx, transaction, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func updateTransaction(ctx context.Context, tx *sql.Tx, transaction *Transaction, userID int64) error {
 	query := `
 	UPDATE transactions
-	SET user_id = $1, 
-		category_id = $2, 
-		description = $3, 
-		amount = $4, 
+	SET category_id = $1, 
+		description = $2, 
+		amount = $3, 
+		occurred_on = $4,
+		currency = $5,
+		account_id = $6,
 		version = version + 1
 	WHERE 
-		id = $5
-		AND user_id = $6
+		id = $7
+		AND category_id IN (
+			SELECT c.id FROM categories c
+			INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id
+			WHERE lm.user_id = $8 AND lm.role <> 'viewer'
+		)
 		AND deleted = false 
-		AND version = $7
+		AND transfer_id IS NULL
+		AND version = $9
 	RETURNING version
 	`
 
 	args := []any{
-		transaction.User.ID,
 		transaction.Category.ID,
 		transaction.Description,
 		transaction.Amount,
+		transaction.OccurredOn,
+		transaction.Currency,
+		nullAccountID(transaction.Account),
 		transaction.ID,
 		userID,
 		transaction.Version,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&transaction.Version,
 	)
 
@@ -394,6 +771,11 @@ func (m TransactionModel) Update(transaction *Transaction, userID int64) error {
 			return err
 		}
 	}
+
+	if transaction.Tags != nil {
+		return setTransactionTags(ctx, tx, transaction.ID, transaction.Tags)
+	}
+
 	return nil
 }
 
@@ -404,8 +786,13 @@ func (m TransactionModel) Delete(id int64, userID int64) error {
 		deleted = true
 	WHERE 
 		id = $1 
-		AND user_id = $2 
+		AND category_id IN (
+			SELECT c.id FROM categories c
+			INNER JOIN ledger_members lm ON lm.ledger_id = c.ledger_id
+			WHERE lm.user_id = $2 AND lm.role <> 'viewer'
+		)
 		AND deleted = false
+		AND transfer_id IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -436,4 +823,11 @@ func ValidateTransaction(v *validator.Validator, transaction *Transaction) {
 	v.Check(len(transaction.Description) <= 500, "description", "must not be more than 500 bytes long")
 	v.Check(transaction.Amount > 0, "amount", "must be positive")
 	v.Check(transaction.Amount != 0, "amount", "must be provided")
+	v.Check(transaction.Amount <= MaxMoney, "amount", "must not be more than 9999999999999.99")
+
+	if transaction.Currency != "" {
+		ValidateCurrency(v, "currency", transaction.Currency)
+	}
+
+	ValidateTags(v, "tags", transaction.Tags)
 }
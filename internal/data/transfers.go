@@ -0,0 +1,353 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"meus_gastos/internal/validator"
+	"time"
+)
+
+type Transfer struct {
+	ID          int64
+	CreatedAt   time.Time
+	User        *User
+	FromAccount *Account
+	ToAccount   *Account
+	Description string
+	Amount      Money
+	OccurredOn  Date
+	Deleted     bool
+	Version     int
+}
+
+type TransferDTO struct {
+	ID          *int64      `json:"transfer_id"`
+	Version     *int        `json:"version"`
+	FromAccount *AccountDTO `json:"from_account"`
+	ToAccount   *AccountDTO `json:"to_account"`
+	Description *string     `json:"description"`
+	Amount      *Money      `json:"amount"`
+	OccurredOn  *Date       `json:"occurred_on"`
+	CreatedAt   *time.Time  `json:"created_at"`
+}
+
+type TransferModel struct {
+	DB *sql.DB
+}
+
+func (t *Transfer) ToDTO() *TransferDTO {
+	dto := &TransferDTO{}
+
+	if t.ID != 0 {
+		dto.ID = &t.ID
+	}
+
+	if t.Version != 0 {
+		dto.Version = &t.Version
+	}
+
+	if t.FromAccount != nil {
+		dto.FromAccount = t.FromAccount.ToDTO()
+	}
+
+	if t.ToAccount != nil {
+		dto.ToAccount = t.ToAccount.ToDTO()
+	}
+
+	if t.Description != "" {
+		dto.Description = &t.Description
+	}
+
+	if t.Amount != 0 {
+		dto.Amount = &t.Amount
+	}
+
+	if !t.OccurredOn.IsZero() {
+		dto.OccurredOn = &t.OccurredOn
+	}
+
+	dto.CreatedAt = &t.CreatedAt
+
+	return dto
+}
+
+func (dto *TransferDTO) ToModel() *Transfer {
+	transfer := &Transfer{}
+
+	if dto.ID != nil {
+		transfer.ID = *dto.ID
+	}
+	if dto.Version != nil {
+		transfer.Version = *dto.Version
+	}
+	if dto.FromAccount != nil {
+		transfer.FromAccount = dto.FromAccount.ToModel()
+	}
+	if dto.ToAccount != nil {
+		transfer.ToAccount = dto.ToAccount.ToModel()
+	}
+	if dto.Description != nil {
+		transfer.Description = *dto.Description
+	}
+	if dto.Amount != nil {
+		transfer.Amount = *dto.Amount
+	}
+	if dto.OccurredOn != nil {
+		transfer.OccurredOn = *dto.OccurredOn
+	}
+
+	return transfer
+}
+
+func (m TransferModel) Insert(transfer *Transfer) error {
+	query := `
+	INSERT INTO transfers (user_id, from_account_id, to_account_id, description, amount, occurred_on)
+	VALUES ($1, $2, $3, $4, $5, COALESCE($6::date, CURRENT_DATE))
+	RETURNING id, created_at, version, occurred_on
+	`
+
+	entryQuery := `
+	INSERT INTO transactions (user_id, account_id, transfer_id, description, amount, occurred_on, currency)
+	SELECT $1, a.id, $3, $4, $5, $6, a.currency
+	FROM accounts a
+	WHERE a.id = $2 AND a.user_id = $1 AND a.deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	args := []any{
+		transfer.User.ID,
+		transfer.FromAccount.ID,
+		transfer.ToAccount.ID,
+		transfer.Description,
+		transfer.Amount,
+		transfer.OccurredOn.nullable(),
+	}
+
+	err = tx.QueryRowContext(ctx, query, args...).Scan(
+		&transfer.ID,
+		&transfer.CreatedAt,
+		&transfer.Version,
+		&transfer.OccurredOn,
+	)
+	if err != nil {
+		return err
+	}
+
+	entries := []struct {
+		accountID int64
+		amount    Money
+	}{
+		{transfer.FromAccount.ID, -transfer.Amount},
+		{transfer.ToAccount.ID, transfer.Amount},
+	}
+
+	for _, entry := range entries {
+		result, err := tx.ExecContext(ctx, entryQuery,
+			transfer.User.ID,
+			entry.accountID,
+			transfer.ID,
+			transfer.Description,
+			entry.amount,
+			transfer.OccurredOn,
+		)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return ErrRecordNotFound
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (m TransferModel) GetByID(id int64, userID int64) (*Transfer, error) {
+	query := `
+	SELECT id, created_at, user_id, from_account_id, to_account_id, description, amount, occurred_on, deleted, version
+	FROM transfers
+	WHERE id = $1 AND user_id = $2 AND deleted = false
+	`
+
+	transfer := Transfer{
+		User:        &User{},
+		FromAccount: &Account{},
+		ToAccount:   &Account{},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.DB.QueryRowContext(ctx, query, id, userID).Scan(
+		&transfer.ID,
+		&transfer.CreatedAt,
+		&transfer.User.ID,
+		&transfer.FromAccount.ID,
+		&transfer.ToAccount.ID,
+		&transfer.Description,
+		&transfer.Amount,
+		&transfer.OccurredOn,
+		&transfer.Deleted,
+		&transfer.Version,
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &transfer, nil
+}
+
+func (m TransferModel) GetAll(userID int64, accountID int64, startDate, endDate *time.Time, filters Filters) ([]*Transfer, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, user_id, from_account_id, to_account_id, description, amount, occurred_on, deleted, version
+	FROM transfers
+	WHERE user_id = $1 AND deleted = false
+	AND ($2 = 0 OR from_account_id = $2 OR to_account_id = $2)
+	AND ($3::date IS NULL OR occurred_on >= $3::date)
+	AND ($4::date IS NULL OR occurred_on <= $4::date)
+	ORDER BY %s %s, id ASC
+	LIMIT $5 OFFSET $6
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	args := []any{
+		userID,
+		accountID,
+		nullDate(startDate),
+		nullDate(endDate),
+		filters.limit(),
+		filters.offset(),
+	}
+
+	rows, err := m.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	transfers := []*Transfer{}
+
+	for rows.Next() {
+		transfer := Transfer{
+			User:        &User{},
+			FromAccount: &Account{},
+			ToAccount:   &Account{},
+		}
+
+		err := rows.Scan(
+			&totalRecords,
+			&transfer.ID,
+			&transfer.CreatedAt,
+			&transfer.User.ID,
+			&transfer.FromAccount.ID,
+			&transfer.ToAccount.ID,
+			&transfer.Description,
+			&transfer.Amount,
+			&transfer.OccurredOn,
+			&transfer.Deleted,
+			&transfer.Version,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		transfers = append(transfers, &transfer)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return transfers, metaData, nil
+}
+
+func (m TransferModel) Delete(id int64, userID int64) error {
+	query := `
+	UPDATE transfers
+	SET
+		deleted = true
+	WHERE
+		id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	entriesQuery := `
+	UPDATE transactions
+	SET
+		deleted = true
+	WHERE
+		transfer_id = $1
+		AND user_id = $2
+		AND deleted = false
+	`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrRecordNotFound
+	}
+
+	_, err = tx.ExecContext(ctx, entriesQuery, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func ValidateTransfer(v *validator.Validator, transfer *Transfer) {
+	v.Check(transfer.User != nil, "user", "must be provided")
+	v.Check(transfer.FromAccount != nil && transfer.FromAccount.ID > 0, "from_account", "must be provided")
+	v.Check(transfer.ToAccount != nil && transfer.ToAccount.ID > 0, "to_account", "must be provided")
+	v.Check(len(transfer.Description) <= 500, "description", "must not be more than 500 bytes long")
+	v.Check(transfer.Amount > 0, "amount", "must be positive")
+	v.Check(transfer.Amount <= MaxMoney, "amount", "must not be more than 9999999999999.99")
+
+	if transfer.FromAccount != nil && transfer.ToAccount != nil {
+		v.Check(transfer.FromAccount.ID != transfer.ToAccount.ID, "to_account", "must be different from from_account")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"meus_gastos/internal/validator"
 	"time"
 
@@ -19,23 +20,39 @@ type User struct {
 	Email     string
 	Password  password
 	Phone     string
+	Currency  string
 	Activated bool
 	Cod       int
 	Version   int
 	Deleted   bool
+
+	CodScope          string
+	CodExpiry         *time.Time
+	CodSentAt         *time.Time
+	CodAttempts       int
+	PasswordChangedAt *time.Time
 }
 
 type UserDTO struct {
-	ID    int64  `json:"user_id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
-	Phone string `json:"phone"`
+	ID       int64  `json:"user_id"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Currency string `json:"currency,omitempty"`
+	Version  int    `json:"version,omitempty"`
+}
+
+type UserAdminDTO struct {
+	*UserDTO
+	Activated bool      `json:"activated"`
+	CreatedAt time.Time `json:"created_at"`
 }
 
 type UserSaveDTO struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Phone    string `json:"phone"`
+	Currency string `json:"currency"`
 	Password string `json:"password"`
 }
 
@@ -44,8 +61,14 @@ type password struct {
 	hash      []byte
 }
 
+const (
+	ScopeActivation    = "activation"
+	ScopePasswordReset = "password-reset"
+)
+
 var (
 	ErrDuplicateEmail = errors.New("duplicate email")
+	ErrDuplicatePhone = errors.New("duplicate phone")
 )
 
 type UserModel struct {
@@ -58,27 +81,44 @@ func (u *User) IsAnonymous() bool {
 
 func (u *User) ToDTO() *UserDTO {
 	return &UserDTO{
-		ID:    u.ID,
-		Name:  u.Name,
-		Email: u.Email,
-		Phone: u.Phone,
+		ID:       u.ID,
+		Name:     u.Name,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Currency: u.Currency,
+		Version:  u.Version,
+	}
+}
+
+func (u *User) ToAdminDTO() *UserAdminDTO {
+	return &UserAdminDTO{
+		UserDTO:   u.ToDTO(),
+		Activated: u.Activated,
+		CreatedAt: u.CreatedAt,
 	}
 }
 
 func (u *UserDTO) ToModel() *User {
 	return &User{
-		ID:    u.ID,
-		Name:  u.Name,
-		Email: u.Email,
-		Phone: u.Phone,
+		ID:       u.ID,
+		Name:     u.Name,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Currency: u.Currency,
+		Version:  u.Version,
 	}
 }
 
 func (u *UserSaveDTO) ToModel() (*User, error) {
 	user := &User{
-		Name:  u.Name,
-		Email: u.Email,
-		Phone: u.Phone,
+		Name:     u.Name,
+		Email:    u.Email,
+		Phone:    u.Phone,
+		Currency: u.Currency,
+	}
+
+	if user.Currency == "" {
+		user.Currency = DefaultCurrency
 	}
 
 	err := user.Password.Set(u.Password)
@@ -89,11 +129,12 @@ func (u *UserSaveDTO) ToModel() (*User, error) {
 	return user, nil
 }
 
-func (m UserModel) GetByCodAndEmail(cod int, email string) (*User, error) {
+func (m UserModel) GetByID(ID int64) (*User, error) {
 	query := `
-	SELECT id, created_at, name, phone, email,cod, password_hash, activated, version
+	SELECT id, created_at, name, phone, email, cod, password_hash, activated, version, currency,
+		cod_scope, cod_expiry, cod_sent_at, cod_attempts, password_changed_at
 	FROM users
-	WHERE email = $1 AND deleted = false AND cod = $2
+	WHERE id = $1 AND deleted = false
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -101,7 +142,7 @@ func (m UserModel) GetByCodAndEmail(cod int, email string) (*User, error) {
 
 	var user User
 
-	err := m.DB.QueryRowContext(ctx, query, email, cod).Scan(
+	err := m.DB.QueryRowContext(ctx, query, ID).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
@@ -111,6 +152,12 @@ func (m UserModel) GetByCodAndEmail(cod int, email string) (*User, error) {
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.Currency,
+		&user.CodScope,
+		&user.CodExpiry,
+		&user.CodSentAt,
+		&user.CodAttempts,
+		&user.PasswordChangedAt,
 	)
 
 	if err != nil {
@@ -125,28 +172,80 @@ func (m UserModel) GetByCodAndEmail(cod int, email string) (*User, error) {
 	return &user, nil
 }
 
-func (m UserModel) GetByID(ID int64) (*User, error) {
-	query := `
-	SELECT id, created_at, name, phone, email, cod, password_hash, activated, version
+func (m UserModel) GetAll(search string, activated *bool, filters Filters) ([]*User, Metadata, error) {
+	query := fmt.Sprintf(`
+	SELECT count(*) OVER(), id, created_at, name, phone, email, activated, version, currency
 	FROM users
-	WHERE email = %1 AND deleted = false
-	`
+	WHERE deleted = false
+	AND ($1 = '' OR name ILIKE '%%' || $1 || '%%' OR email ILIKE '%%' || $1 || '%%')
+	AND ($2::boolean IS NULL OR activated = $2)
+	ORDER BY %s %s, id ASC
+	LIMIT $3 OFFSET $4
+	`, filters.sortColumn(), filters.sortDirection())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.DB.QueryContext(ctx, query, search, activated, filters.limit(), filters.offset())
+	if err != nil {
+		return nil, Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	users := []*User{}
+
+	for rows.Next() {
+		var user User
+
+		err := rows.Scan(
+			&totalRecords,
+			&user.ID,
+			&user.CreatedAt,
+			&user.Name,
+			&user.Phone,
+			&user.Email,
+			&user.Activated,
+			&user.Version,
+			&user.Currency,
+		)
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+
+		users = append(users, &user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, Metadata{}, err
+	}
+
+	metaData := calculateMetadata(totalRecords, filters.Page, filters.PageSize)
+	return users, metaData, nil
+}
+
+func (m UserModel) SetActivated(id int64, activated bool) (*User, error) {
+	query := `
+	UPDATE users SET
+	activated = $1, version = version + 1
+	WHERE id = $2 AND deleted = false
+	RETURNING id, created_at, name, phone, email, activated, version, currency`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
 	var user User
 
-	err := m.DB.QueryRowContext(ctx, query, ID).Scan(
+	err := m.DB.QueryRowContext(ctx, query, activated, id).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Name,
 		&user.Phone,
 		&user.Email,
-		&user.Cod,
-		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.Currency,
 	)
 
 	if err != nil {
@@ -161,11 +260,39 @@ func (m UserModel) GetByID(ID int64) (*User, error) {
 	return &user, nil
 }
 
-func (m UserModel) Insert(user *User) error {
+func (m UserModel) Insert(user *User, ledger *Ledger, permissions ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = insertUser(ctx, tx, user)
+	if err != nil {
+		return err
+	}
+
+	err = addPermissionsForUser(ctx, tx, user.ID, permissions...)
+	if err != nil {
+		return err
+	}
+
+	err = insertLedger(ctx, tx, ledger, user.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func insertUser(ctx context.Context, tx *sql.Tx, user *User) error {
 	query := `
-	INSERT INTO users (name, email, phone,cod, password_hash, activated,deleted)
-	VALUES ($1, $2, $3, $4, $5, $6,false)
-	RETURNING id, created_at, version
+	INSERT INTO users (name, email, phone,cod, password_hash, activated,deleted, currency, cod_scope, cod_expiry, cod_sent_at)
+	VALUES ($1, $2, $3, $4, $5, $6,false, $7, $8, $9, NOW())
+	RETURNING id, created_at, version, cod_sent_at
 	`
 
 	args := []any{
@@ -175,15 +302,16 @@ func (m UserModel) Insert(user *User) error {
 		user.Cod,
 		user.Password.hash,
 		user.Activated,
+		user.Currency,
+		user.CodScope,
+		user.CodExpiry,
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
+	err := tx.QueryRowContext(ctx, query, args...).Scan(
 		&user.ID,
 		&user.CreatedAt,
 		&user.Version,
+		&user.CodSentAt,
 	)
 
 	if err != nil {
@@ -200,7 +328,8 @@ func (m UserModel) Insert(user *User) error {
 
 func (m UserModel) GetByEmail(email string) (*User, error) {
 	query := `
-	SELECT id, created_at, name, phone, email, cod, password_hash, activated, version
+	SELECT id, created_at, name, phone, email, cod, password_hash, activated, version, currency,
+		cod_scope, cod_expiry, cod_sent_at, cod_attempts, password_changed_at
 	FROM users
 	WHERE email = $1 AND deleted = false
 	`
@@ -220,6 +349,12 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 		&user.Password.hash,
 		&user.Activated,
 		&user.Version,
+		&user.Currency,
+		&user.CodScope,
+		&user.CodExpiry,
+		&user.CodSentAt,
+		&user.CodAttempts,
+		&user.PasswordChangedAt,
 	)
 
 	if err != nil {
@@ -237,17 +372,29 @@ func (m UserModel) GetByEmail(email string) (*User, error) {
 func (m UserModel) UpdateCodByEmail(user *User) error {
 	query := `
 	UPDATE users SET
-	cod = $1
-	WHERE id = $2 AND version = $3
-	RETURNING version`
+	cod = $1, cod_scope = $2, cod_expiry = $3, cod_sent_at = NOW(), cod_attempts = 0,
+	version = version + 1
+	WHERE id = $4 AND version = $5
+	RETURNING version, cod_sent_at`
+
+	args := []any{
+		user.Cod,
+		user.CodScope,
+		user.CodExpiry,
+		user.ID,
+		user.Version,
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.DB.QueryRowContext(ctx, query, user.ID, user.Cod, user.Version).Scan(
+	err := m.DB.QueryRowContext(ctx, query, args...).Scan(
 		&user.Version,
+		&user.CodSentAt,
 	)
 
+	user.CodAttempts = 0
+
 	if err != nil {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
@@ -262,12 +409,26 @@ func (m UserModel) UpdateCodByEmail(user *User) error {
 
 }
 
+func (m UserModel) IncrementCodAttempts(user *User) error {
+	query := `
+	UPDATE users SET
+	cod_attempts = cod_attempts + 1
+	WHERE id = $1
+	RETURNING cod_attempts`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return m.DB.QueryRowContext(ctx, query, user.ID).Scan(&user.CodAttempts)
+}
+
 func (m UserModel) Update(user *User) error {
 	query := `
 	UPDATE users SET 
 	name = $1, email = $2, cod = $3, phone = $4, password_hash = $5,
-	activated = $6,version = version + 1
-	WHERE id = $7 AND version = $8
+	activated = $6, currency = $7, cod_scope = $8, cod_expiry = $9, cod_attempts = $10,
+	password_changed_at = $11, version = version + 1
+	WHERE id = $12 AND version = $13
 	RETURNING version`
 
 	args := []any{
@@ -277,6 +438,11 @@ func (m UserModel) Update(user *User) error {
 		user.Phone,
 		user.Password.hash,
 		user.Activated,
+		user.Currency,
+		user.CodScope,
+		user.CodExpiry,
+		user.CodAttempts,
+		user.PasswordChangedAt,
 		user.ID,
 		user.Version,
 	}
@@ -292,6 +458,8 @@ func (m UserModel) Update(user *User) error {
 		switch {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
 			return ErrDuplicateEmail
+		case err.Error() == `pq: duplicate key value violates unique constraint "users_phone_key"`:
+			return ErrDuplicatePhone
 		case errors.Is(err, sql.ErrNoRows):
 			return ErrEditConflict
 		default:
@@ -304,8 +472,8 @@ func (m UserModel) Update(user *User) error {
 func (m UserModel) Delete(user *User) error {
 	query := `
 	UPDATE users set
-	deleted = true
-	where id = $1 AND version = $2
+	deleted = true, version = version + 1
+	where id = $1 AND version = $2 AND deleted = false
 	`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -344,6 +512,8 @@ func ValidateUser(v *validator.Validator, user *User) {
 	v.Check(len(user.Name) <= 500, "name", "must not be more than 500 bytes long")
 	v.Check(user.Phone != "", "phone", "must be provided")
 
+	ValidateCurrency(v, "currency", user.Currency)
+
 	ValidateEmail(v, user.Email)
 
 	if user.Password.plaintext != nil {
@@ -355,6 +525,21 @@ func ValidateUser(v *validator.Validator, user *User) {
 	}
 }
 
+func (u *User) CodValid(cod int, scope string) bool {
+	if u.Cod == 0 || u.Cod != cod || u.CodScope != scope {
+		return false
+	}
+
+	return u.CodExpiry == nil || time.Now().Before(*u.CodExpiry)
+}
+
+func (u *User) ClearCod() {
+	u.Cod = 0
+	u.CodScope = ""
+	u.CodExpiry = nil
+	u.CodAttempts = 0
+}
+
 func (p *password) Set(plaintextPassword string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(plaintextPassword), 12)
 	if err != nil {
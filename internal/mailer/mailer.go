@@ -57,16 +57,16 @@ func (m Mailer) Send(recipient, templateFile string, data any) error {
 	msg.SetHeader("To", recipient)
 	msg.SetHeader("From", m.sender)
 	msg.SetHeader("Subject", subject.String())
-	msg.SetHeader("text/plain", plainBody.String())
-	msg.SetHeader("text/html", htmlBody.String())
+	msg.SetBody("text/plain", plainBody.String())
+	msg.AddAlternative("text/html", htmlBody.String())
 
-	for i := 1; 1 <= 3; i++ {
+	for i := 1; i <= 3; i++ {
 		err = m.dialer.DialAndSend(msg)
-		if err != nil {
+		if err == nil {
 			return nil
 		}
 		time.Sleep(500 * time.Millisecond)
 	}
 
-	return nil
+	return err
 }
@@ -0,0 +1,171 @@
+package ofx
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidFile = errors.New("invalid OFX file")
+)
+
+type Statement struct {
+	BankID       string
+	AccountID    string
+	Currency     string
+	Transactions []*Transaction
+}
+
+type Transaction struct {
+	Type   string
+	Posted time.Time
+	Amount string
+	FITID  string
+	Name   string
+	Memo   string
+	Err    error
+}
+
+func (t *Transaction) Description() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Memo
+}
+
+func Parse(r io.Reader) ([]*Statement, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s := string(content)
+	start := strings.Index(strings.ToUpper(s), "<OFX>")
+	if start < 0 {
+		return nil, ErrInvalidFile
+	}
+	s = s[start:]
+
+	statements := []*Statement{}
+	var statement *Statement
+	var transaction *Transaction
+
+	for {
+		open := strings.IndexByte(s, '<')
+		if open < 0 {
+			break
+		}
+
+		end := strings.IndexByte(s[open:], '>')
+		if end < 0 {
+			return nil, ErrInvalidFile
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(s[open+1 : open+end]))
+		s = s[open+end+1:]
+
+		next := strings.IndexByte(s, '<')
+		if next < 0 {
+			next = len(s)
+		}
+		value := html.UnescapeString(strings.TrimSpace(s[:next]))
+
+		switch {
+		case tag == "STMTRS" || tag == "CCSTMTRS":
+			statement = &Statement{Transactions: []*Transaction{}}
+			statements = append(statements, statement)
+		case tag == "/STMTRS" || tag == "/CCSTMTRS":
+			statement = nil
+		case tag == "STMTTRN":
+			if statement == nil {
+				return nil, ErrInvalidFile
+			}
+			transaction = &Transaction{}
+		case tag == "/STMTTRN":
+			if transaction != nil {
+				transaction.validate()
+				statement.Transactions = append(statement.Transactions, transaction)
+			}
+			transaction = nil
+		case strings.HasPrefix(tag, "/"):
+		case transaction != nil:
+			transaction.set(tag, value)
+		case statement != nil:
+			statement.set(tag, value)
+		}
+	}
+
+	if len(statements) == 0 {
+		return nil, ErrInvalidFile
+	}
+
+	return statements, nil
+}
+
+func (st *Statement) set(tag, value string) {
+	switch tag {
+	case "BANKID":
+		st.BankID = value
+	case "ACCTID":
+		st.AccountID = value
+	case "CURDEF":
+		st.Currency = value
+	}
+}
+
+func (t *Transaction) set(tag, value string) {
+	var err error
+
+	switch tag {
+	case "TRNTYPE":
+		t.Type = value
+	case "DTPOSTED":
+		t.Posted, err = parseDate(value)
+	case "TRNAMT":
+		t.Amount = strings.ReplaceAll(value, ",", ".")
+		if _, err = strconv.ParseFloat(t.Amount, 64); err != nil {
+			err = fmt.Errorf("invalid TRNAMT %q", value)
+		}
+	case "FITID":
+		t.FITID = value
+	case "NAME":
+		t.Name = value
+	case "MEMO":
+		t.Memo = value
+	}
+
+	if err != nil && t.Err == nil {
+		t.Err = err
+	}
+}
+
+func (t *Transaction) validate() {
+	if t.Err != nil {
+		return
+	}
+
+	switch {
+	case t.FITID == "":
+		t.Err = errors.New("missing FITID")
+	case t.Posted.IsZero():
+		t.Err = errors.New("missing DTPOSTED")
+	}
+}
+
+func parseDate(value string) (time.Time, error) {
+	if len(value) < 8 {
+		return time.Time{}, fmt.Errorf("invalid DTPOSTED %q", value)
+	}
+
+	t, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DTPOSTED %q", value)
+	}
+
+	return t, nil
+}
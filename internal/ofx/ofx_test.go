@@ -0,0 +1,210 @@
+package ofx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const sgmlSample = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+SECURITY:NONE
+ENCODING:USASCII
+
+<OFX>
+<SIGNONMSGSRSV1>
+<SONRS>
+<STATUS>
+<CODE>0
+<SEVERITY>INFO
+</STATUS>
+<DTSERVER>20240201120000[-3:BRT]
+<LANGUAGE>POR
+</SONRS>
+</SIGNONMSGSRSV1>
+<BANKMSGSRSV1>
+<STMTTRNRS>
+<STMTRS>
+<CURDEF>BRL
+<BANKACCTFROM>
+<BANKID>0341
+<ACCTID>12345-6
+<ACCTTYPE>CHECKING
+</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240101
+<DTEND>20240131
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240105
+<TRNAMT>-45,90
+<FITID>A1
+<NAME>Padaria &amp; Cia
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240131235959[-3:BRT]
+<TRNAMT>5000.00
+<FITID>A2
+<MEMO>Salario
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240110
+<TRNAMT>-10.00
+<NAME>Sem FITID
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS>
+</STMTTRNRS>
+</BANKMSGSRSV1>
+</OFX>
+`
+
+const xmlSample = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE"?>
+<OFX>
+	<CREDITCARDMSGSRSV1>
+		<CCSTMTTRNRS>
+			<CCSTMTRS>
+				<CURDEF>USD</CURDEF>
+				<CCACCTFROM>
+					<ACCTID>4111</ACCTID>
+				</CCACCTFROM>
+				<BANKTRANLIST>
+					<STMTTRN>
+						<TRNTYPE>DEBIT</TRNTYPE>
+						<DTPOSTED>20240301093000.000[+9:JST]</DTPOSTED>
+						<TRNAMT>-12.34</TRNAMT>
+						<FITID>X1</FITID>
+						<NAME>Coffee</NAME>
+						<MEMO>Morning</MEMO>
+					</STMTTRN>
+					<STMTTRN>
+						<TRNTYPE>CREDIT</TRNTYPE>
+						<DTPOSTED>2024030</DTPOSTED>
+						<TRNAMT>abc</TRNAMT>
+						<FITID>X2</FITID>
+					</STMTTRN>
+				</BANKTRANLIST>
+			</CCSTMTRS>
+		</CCSTMTTRNRS>
+	</CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestParseSGML(t *testing.T) {
+	statements, err := Parse(strings.NewReader(sgmlSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statements) != 1 {
+		t.Fatalf("got %d statements, want 1", len(statements))
+	}
+
+	st := statements[0]
+	if st.BankID != "0341" || st.AccountID != "12345-6" || st.Currency != "BRL" {
+		t.Errorf("got statement %q %q %q, want 0341 12345-6 BRL", st.BankID, st.AccountID, st.Currency)
+	}
+
+	if len(st.Transactions) != 3 {
+		t.Fatalf("got %d transactions, want 3", len(st.Transactions))
+	}
+
+	tests := []struct {
+		fitid       string
+		posted      time.Time
+		amount      string
+		description string
+		err         string
+	}{
+		{"A1", time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC), "-45.90", "Padaria & Cia", ""},
+		{"A2", time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), "5000.00", "Salario", ""},
+		{"", time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC), "-10.00", "Sem FITID", "missing FITID"},
+	}
+
+	for i, tt := range tests {
+		got := st.Transactions[i]
+
+		if got.FITID != tt.fitid {
+			t.Errorf("transaction %d: got FITID %q, want %q", i, got.FITID, tt.fitid)
+		}
+		if !got.Posted.Equal(tt.posted) {
+			t.Errorf("transaction %d: got DTPOSTED %v, want %v", i, got.Posted, tt.posted)
+		}
+		if got.Amount != tt.amount {
+			t.Errorf("transaction %d: got TRNAMT %q, want %q", i, got.Amount, tt.amount)
+		}
+		if got.Description() != tt.description {
+			t.Errorf("transaction %d: got description %q, want %q", i, got.Description(), tt.description)
+		}
+
+		switch {
+		case tt.err == "" && got.Err != nil:
+			t.Errorf("transaction %d: unexpected error: %v", i, got.Err)
+		case tt.err != "" && (got.Err == nil || got.Err.Error() != tt.err):
+			t.Errorf("transaction %d: got error %v, want %q", i, got.Err, tt.err)
+		}
+	}
+}
+
+func TestParseXML(t *testing.T) {
+	statements, err := Parse(strings.NewReader(xmlSample))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statements) != 1 {
+		t.Fatalf("got %d statements, want 1", len(statements))
+	}
+
+	st := statements[0]
+	if st.AccountID != "4111" || st.Currency != "USD" {
+		t.Errorf("got statement %q %q, want 4111 USD", st.AccountID, st.Currency)
+	}
+
+	if len(st.Transactions) != 2 {
+		t.Fatalf("got %d transactions, want 2", len(st.Transactions))
+	}
+
+	first := st.Transactions[0]
+	if first.Err != nil {
+		t.Fatalf("unexpected error: %v", first.Err)
+	}
+	if want := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); !first.Posted.Equal(want) {
+		t.Errorf("got DTPOSTED %v, want %v", first.Posted, want)
+	}
+	if first.Amount != "-12.34" || first.Description() != "Coffee" {
+		t.Errorf("got %q %q, want -12.34 Coffee", first.Amount, first.Description())
+	}
+
+	second := st.Transactions[1]
+	if second.Err == nil || !strings.Contains(second.Err.Error(), "DTPOSTED") {
+		t.Errorf("got error %v, want the first error to report DTPOSTED", second.Err)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"no ofx root", "<HTML><BODY>nothing</BODY></HTML>"},
+		{"no statements", "<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>"},
+		{"unterminated tag", "<OFX><STMTRS"},
+		{"transaction outside statement", "<OFX><STMTTRN><FITID>1</STMTTRN></OFX>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input))
+			if !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("got error %v, want ErrInvalidFile", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE recurring_transactions (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    description VARCHAR(500) NOT NULL,
+    amount NUMERIC(15,2) NOT NULL,
+    frequency INTEGER NOT NULL CHECK (frequency IN (1, 2, 3, 4)),
+    start_date DATE NOT NULL,
+    end_date DATE,
+    occurrences INTEGER CHECK (occurrences > 0),
+    occurrences_generated INTEGER NOT NULL DEFAULT 0,
+    next_run DATE NOT NULL,
+    finished BOOLEAN NOT NULL DEFAULT FALSE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_recurring_transactions_user_id ON recurring_transactions(user_id) WHERE NOT deleted;
+CREATE INDEX idx_recurring_transactions_next_run ON recurring_transactions(next_run) WHERE NOT deleted AND NOT finished;
+CREATE INDEX idx_recurring_transactions_description_search ON recurring_transactions USING GIN (to_tsvector('simple', description));
+
+ALTER TABLE transactions ADD COLUMN recurring_transaction_id BIGINT REFERENCES recurring_transactions(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN recurring_date DATE;
+
+CREATE UNIQUE INDEX idx_transactions_recurring_occurrence ON transactions(recurring_transaction_id, recurring_date) WHERE recurring_transaction_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_recurring_occurrence;
+ALTER TABLE transactions DROP COLUMN IF EXISTS recurring_date;
+ALTER TABLE transactions DROP COLUMN IF EXISTS recurring_transaction_id;
+DROP TABLE IF EXISTS recurring_transactions;
+-- +goose StatementEnd
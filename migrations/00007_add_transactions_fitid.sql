@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN external_account VARCHAR(64);
+ALTER TABLE transactions ADD COLUMN fitid VARCHAR(255);
+
+CREATE UNIQUE INDEX idx_transactions_user_fitid ON transactions(user_id, external_account, fitid) WHERE fitid IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_user_fitid;
+ALTER TABLE transactions DROP COLUMN IF EXISTS fitid;
+ALTER TABLE transactions DROP COLUMN IF EXISTS external_account;
+-- +goose StatementEnd
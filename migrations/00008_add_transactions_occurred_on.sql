@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE transactions ADD COLUMN occurred_on DATE;
+
+UPDATE transactions SET occurred_on = COALESCE(recurring_date, created_at::date);
+
+ALTER TABLE transactions ALTER COLUMN occurred_on SET NOT NULL;
+ALTER TABLE transactions ALTER COLUMN occurred_on SET DEFAULT CURRENT_DATE;
+
+CREATE INDEX idx_transactions_user_occurred_on ON transactions(user_id, occurred_on) WHERE NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_user_occurred_on;
+ALTER TABLE transactions DROP COLUMN IF EXISTS occurred_on;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+ALTER TABLE transactions ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    base_currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    rate_date DATE NOT NULL,
+    rate NUMERIC(18, 8) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (base_currency, quote_currency, rate_date)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS exchange_rates;
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
+ALTER TABLE users DROP COLUMN IF EXISTS currency;
+-- +goose StatementEnd
@@ -0,0 +1,51 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE accounts (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type INTEGER NOT NULL CHECK (type IN (1, 2, 3, 4)),
+    currency CHAR(3) NOT NULL DEFAULT 'BRL',
+    initial_balance NUMERIC(15,2) NOT NULL DEFAULT 0,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE UNIQUE INDEX idx_accounts_user_name ON accounts(user_id, LOWER(name)) WHERE NOT deleted;
+
+CREATE TABLE transfers (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    to_account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    description VARCHAR(500) NOT NULL,
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    occurred_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (from_account_id <> to_account_id)
+);
+
+CREATE INDEX idx_transfers_user_occurred_on ON transfers(user_id, occurred_on) WHERE NOT deleted;
+
+ALTER TABLE transactions ADD COLUMN account_id BIGINT REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE transactions ADD COLUMN transfer_id BIGINT REFERENCES transfers(id) ON DELETE CASCADE;
+ALTER TABLE transactions ALTER COLUMN category_id DROP NOT NULL;
+ALTER TABLE transactions ADD CONSTRAINT transactions_category_or_transfer CHECK ((category_id IS NULL) = (transfer_id IS NOT NULL));
+
+CREATE INDEX idx_transactions_account_id ON transactions(account_id, occurred_on) WHERE NOT deleted;
+CREATE INDEX idx_transactions_transfer_id ON transactions(transfer_id) WHERE transfer_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM transactions WHERE transfer_id IS NOT NULL;
+ALTER TABLE transactions DROP CONSTRAINT IF EXISTS transactions_category_or_transfer;
+ALTER TABLE transactions ALTER COLUMN category_id SET NOT NULL;
+ALTER TABLE transactions DROP COLUMN IF EXISTS transfer_id;
+ALTER TABLE transactions DROP COLUMN IF EXISTS account_id;
+DROP TABLE IF EXISTS transfers;
+DROP TABLE IF EXISTS accounts;
+-- +goose StatementEnd
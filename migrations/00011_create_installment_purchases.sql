@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE accounts ADD COLUMN closing_day SMALLINT CHECK (closing_day BETWEEN 1 AND 31);
+ALTER TABLE accounts ADD COLUMN due_day SMALLINT CHECK (due_day BETWEEN 1 AND 31);
+
+CREATE TABLE installment_purchases (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id BIGINT NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    description VARCHAR(500) NOT NULL,
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    installments INTEGER NOT NULL CHECK (installments BETWEEN 1 AND 48),
+    occurred_on DATE NOT NULL,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_installment_purchases_user_id ON installment_purchases(user_id) WHERE NOT deleted;
+
+ALTER TABLE transactions ADD COLUMN installment_purchase_id BIGINT REFERENCES installment_purchases(id) ON DELETE CASCADE;
+ALTER TABLE transactions ADD COLUMN installment_number INTEGER;
+
+CREATE UNIQUE INDEX idx_transactions_installment ON transactions(installment_purchase_id, installment_number) WHERE installment_purchase_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_transactions_installment;
+ALTER TABLE transactions DROP COLUMN IF EXISTS installment_number;
+ALTER TABLE transactions DROP COLUMN IF EXISTS installment_purchase_id;
+DROP TABLE IF EXISTS installment_purchases;
+ALTER TABLE accounts DROP COLUMN IF EXISTS due_day;
+ALTER TABLE accounts DROP COLUMN IF EXISTS closing_day;
+-- +goose StatementEnd
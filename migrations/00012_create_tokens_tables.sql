@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE refresh_tokens (
+    hash BYTEA PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    revoked BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id) WHERE NOT revoked;
+CREATE INDEX idx_refresh_tokens_expiry ON refresh_tokens(expiry);
+
+CREATE TABLE revoked_access_tokens (
+    jti TEXT PRIMARY KEY,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_revoked_access_tokens_expiry ON revoked_access_tokens(expiry);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS revoked_access_tokens;
+DROP TABLE IF EXISTS refresh_tokens;
+-- +goose StatementEnd
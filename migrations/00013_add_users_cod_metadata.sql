@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN cod_scope TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN cod_expiry TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN cod_sent_at TIMESTAMP(0) WITH TIME ZONE;
+ALTER TABLE users ADD COLUMN cod_attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN password_changed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE users SET cod_scope = 'activation' WHERE NOT activated AND cod IS NOT NULL AND cod <> 0;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS password_changed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS cod_attempts;
+ALTER TABLE users DROP COLUMN IF EXISTS cod_sent_at;
+ALTER TABLE users DROP COLUMN IF EXISTS cod_expiry;
+ALTER TABLE users DROP COLUMN IF EXISTS cod_scope;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+UPDATE users SET cod_expiry = NOW() + INTERVAL '24 hours'
+WHERE cod_scope = 'activation' AND cod_expiry IS NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+SELECT 1;
+-- +goose StatementEnd
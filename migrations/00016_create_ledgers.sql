@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE ledgers (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    name VARCHAR(200) NOT NULL,
+    owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_ledgers_owner_id ON ledgers(owner_id);
+
+CREATE TABLE ledger_members (
+    ledger_id BIGINT NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (ledger_id, user_id)
+);
+
+CREATE INDEX idx_ledger_members_user_id ON ledger_members(user_id);
+
+CREATE TABLE ledger_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ledger_id BIGINT NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    email CITEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('editor', 'viewer')),
+    invited_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    hash BYTEA NOT NULL UNIQUE,
+    expiry TIMESTAMP(0) WITH TIME ZONE NOT NULL,
+    accepted_at TIMESTAMP(0) WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX idx_ledger_invitations_pending ON ledger_invitations(ledger_id, email) WHERE accepted_at IS NULL;
+
+INSERT INTO ledgers (name, owner_id)
+SELECT 'Pessoal', id FROM users;
+
+INSERT INTO ledger_members (ledger_id, user_id, role)
+SELECT id, owner_id, 'owner' FROM ledgers;
+
+ALTER TABLE categories ADD COLUMN ledger_id BIGINT REFERENCES ledgers(id) ON DELETE CASCADE;
+
+UPDATE categories c SET ledger_id = l.id
+FROM ledgers l
+WHERE l.owner_id = c.user_id;
+
+ALTER TABLE categories ALTER COLUMN ledger_id SET NOT NULL;
+
+CREATE INDEX idx_categories_ledger_id ON categories(ledger_id) WHERE NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_categories_ledger_id;
+ALTER TABLE categories DROP COLUMN IF EXISTS ledger_id;
+DROP TABLE IF EXISTS ledger_invitations;
+DROP TABLE IF EXISTS ledger_members;
+DROP TABLE IF EXISTS ledgers;
+-- +goose StatementEnd
@@ -0,0 +1,34 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE transaction_splits (
+    transaction_id BIGINT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (transaction_id, user_id)
+);
+
+CREATE INDEX idx_transaction_splits_user_id ON transaction_splits(user_id);
+
+CREATE TABLE settlements (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ledger_id BIGINT NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    from_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount NUMERIC(15,2) NOT NULL CHECK (amount > 0),
+    currency CHAR(3) NOT NULL,
+    occurred_on DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_by BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    CHECK (from_user_id <> to_user_id)
+);
+
+CREATE INDEX idx_settlements_ledger_id ON settlements(ledger_id) WHERE NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS settlements;
+DROP TABLE IF EXISTS transaction_splits;
+-- +goose StatementEnd
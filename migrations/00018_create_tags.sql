@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tags (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    ledger_id BIGINT NOT NULL REFERENCES ledgers(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    UNIQUE (ledger_id, name)
+);
+
+CREATE TABLE transaction_tags (
+    transaction_id BIGINT NOT NULL REFERENCES transactions(id) ON DELETE CASCADE,
+    tag_id BIGINT NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (transaction_id, tag_id)
+);
+
+CREATE INDEX idx_transaction_tags_tag_id ON transaction_tags(tag_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS transaction_tags;
+DROP TABLE IF EXISTS tags;
+-- +goose StatementEnd
@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE categories ADD COLUMN parent_id BIGINT REFERENCES categories(id);
+
+CREATE INDEX idx_categories_parent_id ON categories(parent_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_categories_parent_id;
+
+ALTER TABLE categories DROP COLUMN IF EXISTS parent_id;
+-- +goose StatementEnd
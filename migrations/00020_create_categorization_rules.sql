@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE categorization_rules (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP(0) WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id BIGINT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    priority INTEGER NOT NULL DEFAULT 100,
+    description_match VARCHAR(10) NOT NULL DEFAULT 'any' CHECK (description_match IN ('any', 'contains', 'regex')),
+    description_pattern TEXT NOT NULL DEFAULT '',
+    min_amount NUMERIC(15,2),
+    max_amount NUMERIC(15,2),
+    account_id BIGINT REFERENCES accounts(id) ON DELETE CASCADE,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    deleted BOOLEAN NOT NULL DEFAULT false,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE INDEX idx_categorization_rules_user_priority ON categorization_rules(user_id, priority) WHERE deleted = false;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS categorization_rules;
+-- +goose StatementEnd
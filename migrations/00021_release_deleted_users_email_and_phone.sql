@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_phone_key;
+
+CREATE UNIQUE INDEX users_email_key ON users(email) WHERE NOT deleted;
+CREATE UNIQUE INDEX users_phone_key ON users(phone) WHERE NOT deleted;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS users_email_key;
+DROP INDEX IF EXISTS users_phone_key;
+
+ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);
+ALTER TABLE users ADD CONSTRAINT users_phone_key UNIQUE (phone);
+-- +goose StatementEnd